@@ -7,3 +7,6 @@ go build -v -o ./bin/gymaster ./gymaster
 echo Building Gargoyle Slave...
 # rm -f ./gymaster/*.syso
 go build -v -o ./bin/gyslave ./gyslave
+
+echo Building Gargoyle Local Judge...
+go build -v -o ./bin/gyjudge ./gyjudge
@@ -10,5 +10,8 @@ rem windres gyslave/winappres.rc -o gyslave/winappres.syso
 go build -v -o ./bin/gyslave.exe ./gyslave
 rem del gyslave\*.syso
 
+echo Building Gargoyle Local Judge...
+go build -v -o ./bin/gyjudge.exe ./gyjudge
+
 echo Done! Press any key to exit...
 pause>nul
\ No newline at end of file
@@ -0,0 +1,188 @@
+package main
+
+/* GargoyleJudge - Simple Judgement System for Competitive Programming
+ * Copyright (C) Thiekus 2019
+ * Visit www.khayalan.id for updates
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/. */
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"github.com/thiekus/gargoyle-judge/internal/gylib"
+	"github.com/thiekus/gargoyle-judge/internal/gyrpc"
+	"github.com/thiekus/gargoyle-judge/internal/gytask"
+	"github.com/thiekus/gargoyle-judge/internal/gytypes"
+)
+
+const appVersion = "0.1r1"
+
+// Exit codes, so CI can tell rejected solution from broken setup
+const (
+	exitAccepted = 0
+	exitRejected = 1
+	exitError    = 2
+)
+
+type testFilePair struct {
+	name       string
+	inputPath  string
+	outputPath string
+}
+
+// Collect *.in and *.out pairs, numbered test names sorted numerically
+func getTestFilePairs(dir string) ([]testFilePair, error) {
+	inputs, err := filepath.Glob(filepath.Join(dir, "*.in"))
+	if err != nil {
+		return nil, err
+	}
+	var pairs []testFilePair
+	for _, input := range inputs {
+		name := strings.TrimSuffix(filepath.Base(input), ".in")
+		output := strings.TrimSuffix(input, ".in") + ".out"
+		if !gylib.IsFileExists(output) {
+			return nil, fmt.Errorf("test %s doesn't have output file", name)
+		}
+		pairs = append(pairs, testFilePair{
+			name:       name,
+			inputPath:  input,
+			outputPath: output,
+		})
+	}
+	sort.Slice(pairs, func(i, j int) bool {
+		ni, errI := strconv.Atoi(pairs[i].name)
+		nj, errJ := strconv.Atoi(pairs[j].name)
+		if (errI == nil) && (errJ == nil) {
+			return ni < nj
+		}
+		return pairs[i].name < pairs[j].name
+	})
+	return pairs, nil
+}
+
+func loadLanguageData(path string) (gytypes.LanguageProgramData, error) {
+	lang := gytypes.LanguageProgramData{}
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return lang, err
+	}
+	if err = json.Unmarshal(b, &lang); err != nil {
+		return lang, err
+	}
+	if (lang.SourceName == "") || (lang.ExecuteCommand == "") {
+		return lang, errors.New("language definition must have SourceName and ExecuteCommand")
+	}
+	return lang, nil
+}
+
+func runJudge() (int, error) {
+	langPath := flag.String("lang", "", "language definition JSON file (fields of LanguageProgramData)")
+	testDir := flag.String("tests", ".", "directory contains *.in and *.out test pairs")
+	timeLimit := flag.Int("time", 1000, "problem time limit in milliseconds")
+	memLimit := flag.Int("mem", 256, "problem memory limit in MB")
+	timingRerun := flag.Int("rerun", 3, "maximum runs for borderline test case timing")
+	timingBand := flag.Float64("band", 0.1, "fraction around time limit considered as borderline")
+	verbose := flag.Bool("v", false, "print judging log")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s -lang <lang.json> [options] <source file>\n", filepath.Base(os.Args[0]))
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if (flag.NArg() != 1) || (*langPath == "") {
+		flag.Usage()
+		return exitError, errors.New("source file and language definition are required")
+	}
+	log := gylib.GetStdLog()
+	if !*verbose {
+		log.SetLevel(logrus.WarnLevel)
+	}
+	lang, err := loadLanguageData(*langPath)
+	if err != nil {
+		return exitError, err
+	}
+	code, err := ioutil.ReadFile(flag.Arg(0))
+	if err != nil {
+		return exitError, err
+	}
+	pairs, err := getTestFilePairs(*testDir)
+	if err != nil {
+		return exitError, err
+	}
+	if len(pairs) == 0 {
+		return exitError, fmt.Errorf("no test pairs found in %s", *testDir)
+	}
+	var tests []gytypes.TestCaseData
+	for i, pair := range pairs {
+		input, err := ioutil.ReadFile(pair.inputPath)
+		if err != nil {
+			return exitError, err
+		}
+		output, err := ioutil.ReadFile(pair.outputPath)
+		if err != nil {
+			return exitError, err
+		}
+		tests = append(tests, gytypes.TestCaseData{
+			TestNo: i + 1,
+			Input:  string(input),
+			Output: string(output),
+		})
+	}
+	// Server is never listening, only used to run task in-process
+	server, err := gyrpc.NewGargoyleRpcServer("", gytask.SlaveTaskHandler{})
+	if err != nil {
+		return exitError, err
+	}
+	req := gyrpc.RpcSubmissionRequest{
+		Submission: gytypes.SubmissionData{
+			Code:    string(code),
+			Verdict: gytypes.SubmissionOnQueue,
+		},
+		ProgramLang: lang,
+		ProblemDetails: gytypes.ProblemData{
+			Name:      filepath.Base(*testDir),
+			TimeLimit: *timeLimit,
+			MemLimit:  *memLimit,
+		},
+		TestCases:   tests,
+		TimingRerun: *timingRerun,
+		TimingBand:  *timingBand,
+	}
+	resp, err := server.ProcessLocalSubmission(req)
+	if err != nil {
+		return exitError, err
+	}
+	sub := resp.Submission
+	if sub.Verdict == gytypes.SubmissionCompilerError {
+		fmt.Println(sub.CompileStdout)
+		fmt.Println(sub.CompileStderr)
+	}
+	for _, result := range resp.TestResults {
+		fmt.Printf("%-12s %s %10.3fms %10dKB\n", pairs[result.TestNo-1].name, result.Verdict, result.TimeElapsed, result.MemoryUsed/1024)
+	}
+	fmt.Printf("Verdict: %s (%s), score %d\n", sub.Verdict, sub.GetStatusMessage(), sub.Score)
+	if !sub.IsSuccess() {
+		return exitRejected, nil
+	}
+	return exitAccepted, nil
+}
+
+// Local judge, judge single source file against test directory without master and slave server
+func main() {
+	code, err := runJudge()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", err.Error())
+	}
+	os.Exit(code)
+}
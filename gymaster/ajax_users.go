@@ -60,3 +60,22 @@ func ajaxReadAllNotifications(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 }
+
+func ajaxGetQueueStatus(w http.ResponseWriter, r *http.Request) {
+	ui := appUsers.GetLoggedUserInfo(r)
+	if ui == nil {
+		http.Error(w, "403 Forbidden", http.StatusForbidden)
+		return
+	}
+	status, err := appJudgeQueue.GetStatus(ui.Id, ui.IsJury() || ui.IsAdmin())
+	if err != nil {
+		http.Error(w, "500 Internal Server Error: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if data, err := json.Marshal(status); err == nil {
+		w.Write(data)
+	} else {
+		http.Error(w, "500 Internal Server Error: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
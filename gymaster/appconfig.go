@@ -21,48 +21,75 @@ import (
 )
 
 type ConfigData struct {
-	HasFirstSetup bool    `json:"hasFirstSetup"`
-	TimeUTC       float64 `json:"timeUTC"`
-	SessionKey    string  `json:"sessionKey"`
-	Hostname      string  `json:"hostname"`
-	ListeningPort int     `json:"listeningPort"`
-	RootSubPath   string  `json:"rootSubPath"`
-	UseTLS        bool    `json:"useTLS"`
-	ForceTLS      bool    `json:"forceTLS"`
-	CrtFile       string  `json:"crtFile"`
-	KeyFile       string  `json:"keyFile"`
-	CompressOnFly bool    `json:"compressOnFly"`
-	PageMinify    bool    `json:"pageMinify"`
-	AssetsCaching bool    `json:"assetsCaching"`
-	AssetsMinify  bool    `json:"assetsMinify"`
-	DbDriver      string  `json:"dbDriver"`
-	DbHost        string  `json:"dbHost"`
-	DbUsername    string  `json:"dbUsername"`
-	DbPassword    string  `json:"dbPassword"`
-	DbFile        string  `json:"dbFile"`
-	DbName        string  `json:"dbName"`
-	DbTablePrefix string  `json:"dbTablePrefix"`
+	HasFirstSetup    bool     `json:"hasFirstSetup"`
+	TimeUTC          float64  `json:"timeUTC"`
+	SessionKey       string   `json:"sessionKey"`
+	Hostname         string   `json:"hostname"`
+	ListeningPort    int      `json:"listeningPort"`
+	RootSubPath      string   `json:"rootSubPath"`
+	UseTLS           bool     `json:"useTLS"`
+	ForceTLS         bool     `json:"forceTLS"`
+	CrtFile          string   `json:"crtFile"`
+	KeyFile          string   `json:"keyFile"`
+	CompressOnFly    bool     `json:"compressOnFly"`
+	PageMinify       bool     `json:"pageMinify"`
+	AssetsCaching    bool     `json:"assetsCaching"`
+	AssetsMinify     bool     `json:"assetsMinify"`
+	DbDriver         string   `json:"dbDriver"`
+	DbHost           string   `json:"dbHost"`
+	DbUsername       string   `json:"dbUsername"`
+	DbPassword       string   `json:"dbPassword"`
+	DbFile           string   `json:"dbFile"`
+	DbName           string   `json:"dbName"`
+	DbTablePrefix    string   `json:"dbTablePrefix"`
+	TimingRerun      int      `json:"timingRerun"`
+	TimingBand       float64  `json:"timingBand"`
+	EmbeddedSlave    bool     `json:"embeddedSlave"`
+	JudgeWorkers     int      `json:"judgeWorkers"`
+	JudgeLease       int      `json:"judgeLease"`
+	JudgeRetry       int      `json:"judgeRetry"`
+	JudgeBackoff     int      `json:"judgeBackoff"`
+	SlaveToken       string   `json:"slaveToken"`
+	JudgeFairByGroup bool     `json:"judgeFairByGroup"`
+	SubmitCooldown   int      `json:"submitCooldown"`
+	JudgeDoubleRate  float64  `json:"judgeDoubleRate"`
+	JudgeDoubleNear  bool     `json:"judgeDoubleNearLimit"`
+	IOITieBreakers   []string `json:"ioiTieBreakers"` // Empty means tied contestants share rank
+	FirstSolveGroup  bool     `json:"firstSolveByGroup"`
 }
 
 const (
-	ConfigDefaultHasFirstSetup = false
-	ConfigDefaultTimeUTC       = 7 // UTC +7 WIB
-	ConfigDefaultListeningPort = 28498
-	ConfigDefaultRootSubPath   = "/"
-	ConfigDefaultUseTLS        = false
-	ConfigDefaultCrtFile       = "./cert/server.crt"
-	ConfigDefaultKeyFile       = "./cert/server.key"
-	ConfigDefaultCompressOnFly = true
-	ConfigDefaultPageMinify    = true
-	ConfigDefaultAssetsCaching = true
-	ConfigDefaultAssetsMinify  = true
-	ConfigDefaultDbDriver      = "mysql"
-	ConfigDefaultDbHost        = "localhost"
-	ConfigDefaultDbUsername    = "root"
-	ConfigDefaultDbPassword    = ""
-	ConfigDefaultDbName        = "gargoyle"
-	ConfigDefaultDbFile        = "./database.db"
-	ConfigDefaultDbTablePrefix = "gy_"
+	ConfigDefaultHasFirstSetup    = false
+	ConfigDefaultTimeUTC          = 7 // UTC +7 WIB
+	ConfigDefaultListeningPort    = 28498
+	ConfigDefaultRootSubPath      = "/"
+	ConfigDefaultUseTLS           = false
+	ConfigDefaultCrtFile          = "./cert/server.crt"
+	ConfigDefaultKeyFile          = "./cert/server.key"
+	ConfigDefaultCompressOnFly    = true
+	ConfigDefaultPageMinify       = true
+	ConfigDefaultAssetsCaching    = true
+	ConfigDefaultAssetsMinify     = true
+	ConfigDefaultDbDriver         = "mysql"
+	ConfigDefaultDbHost           = "localhost"
+	ConfigDefaultDbUsername       = "root"
+	ConfigDefaultDbPassword       = ""
+	ConfigDefaultDbName           = "gargoyle"
+	ConfigDefaultDbFile           = "./database.db"
+	ConfigDefaultDbTablePrefix    = "gy_"
+	ConfigDefaultTimingRerun      = 3   // Run borderline test case up to 3 times, 1 or less disables
+	ConfigDefaultTimingBand       = 0.1 // Borderline if within 10% around time limit, 0 disables
+	ConfigDefaultEmbeddedSlave    = false
+	ConfigDefaultJudgeWorkers     = 4
+	ConfigDefaultJudgeLease       = 600  // in seconds
+	ConfigDefaultJudgeRetry       = 3    // Judging attempts before giving up as SE
+	ConfigDefaultJudgeBackoff     = 1000 // in ms, doubled after each failed attempt
+	ConfigDefaultSlaveToken       = ""   // Empty disables slave self-registration
+	ConfigDefaultJudgeFairByGroup = false
+	ConfigDefaultSubmitCooldown   = 0 // in seconds, 0 disables cooldown
+	ConfigDefaultJudgeDoubleRate  = 0 // Fraction of submissions judged twice, 0 disables
+	ConfigDefaultJudgeDoubleNear  = false
+	ConfigDefaultFirstSolveGroup  = false
 )
 
 const ConfigFilename = "master_config.json"
@@ -93,8 +120,24 @@ func getConfigData() ConfigData {
 		cfg.DbName = ConfigDefaultDbName
 		cfg.DbFile = ConfigDefaultDbFile
 		cfg.DbTablePrefix = ConfigDefaultDbTablePrefix
+		cfg.TimingRerun = ConfigDefaultTimingRerun
+		cfg.TimingBand = ConfigDefaultTimingBand
+		cfg.EmbeddedSlave = ConfigDefaultEmbeddedSlave
+		cfg.JudgeWorkers = ConfigDefaultJudgeWorkers
+		cfg.JudgeLease = ConfigDefaultJudgeLease
+		cfg.JudgeRetry = ConfigDefaultJudgeRetry
+		cfg.JudgeBackoff = ConfigDefaultJudgeBackoff
+		cfg.SlaveToken = ConfigDefaultSlaveToken
+		cfg.JudgeFairByGroup = ConfigDefaultJudgeFairByGroup
+		cfg.SubmitCooldown = ConfigDefaultSubmitCooldown
+		cfg.JudgeDoubleRate = ConfigDefaultJudgeDoubleRate
+		cfg.JudgeDoubleNear = ConfigDefaultJudgeDoubleNear
+		cfg.FirstSolveGroup = ConfigDefaultFirstSolveGroup
 		saveConfigData(cfg)
 	}
+	// Config made before re-run existed has no such keys, zero given there means disabled
+	cfg.TimingRerun = ConfigDefaultTimingRerun
+	cfg.TimingBand = ConfigDefaultTimingBand
 	if jsonData, err := ioutil.ReadFile(configPath); err == nil {
 		if err = json.Unmarshal(jsonData, &cfg); err == nil {
 			log.Print("Config data loaded...")
@@ -12,6 +12,8 @@ import (
 	"compress/gzip"
 	"context"
 	"encoding/base64"
+	"errors"
+	"flag"
 	"fmt"
 	"io/ioutil"
 	"net/http"
@@ -24,6 +26,8 @@ import (
 	"github.com/NYTimes/gziphandler"
 	"github.com/gorilla/mux"
 	"github.com/thiekus/gargoyle-judge/internal/gylib"
+	"github.com/thiekus/gargoyle-judge/internal/gyrpc"
+	"github.com/thiekus/gargoyle-judge/internal/gytypes"
 )
 
 const appVersion = "0.8r284"
@@ -37,10 +41,12 @@ var appOnShutdown = false
 var appOnRestart = false
 var appUsers UserController
 var appSlaves SlaveManager
+var appJudgeQueue JudgeQueue
 var appContestAccess ContestAccessController
 var appLangPrograms LanguageProgramController
 var appScoreboard ScoreboardController
 var appNotifications NotificationController
+var appLiveUpdates LiveUpdateController
 
 func FixRootPath(oldPath string) string {
 	if oldPath == "/" {
@@ -86,6 +92,10 @@ func shutdownEndpoint(w http.ResponseWriter, r *http.Request) {
 		log := gylib.GetStdLog()
 		log.Print("Requesting shutdown...")
 		appOnShutdown = true
+		appJudgeQueue.Stop()
+		appSlaves.StopHealthCheck()
+		appScoreboard.StopUnfreezeScheduler()
+		appLiveUpdates.Stop()
 		go func() {
 			time.Sleep(5000 * time.Millisecond)
 			log.Print("Shutting down...")
@@ -183,7 +193,8 @@ func prepareConfig() {
 	appConfig = getConfigData()
 }
 
-func prepareDatabase() {
+// Returns error only if database schema can't be migrated, server must not run on it
+func prepareDatabase() error {
 	log := gylib.GetStdLog()
 	log.Print("Testing database connection...")
 	log.Printf("DB Driver: %s", appConfig.DbDriver)
@@ -201,15 +212,42 @@ func prepareDatabase() {
 	}
 	if !appConfig.HasFirstSetup {
 		log.Warn("You seems never do First Setup. Please do that before someone broke your mind!")
+	} else {
+		log.Print("Checking for database migrations...")
+		if err = MigrateDatabase(); err != nil {
+			log.Errorf("Migration error: %s", err.Error())
+			return err
+		}
 	}
+	return nil
+}
+
+// Start slave health checking, judge queue workers and scoreboard unfreeze scheduler, needs database ready
+func startJudging() {
+	appSlaves.StartHealthCheck()
+	if err := appJudgeQueue.Start(); err != nil {
+		log := gylib.GetStdLog()
+		log.Errorf("Cannot start judge queue: %s", err.Error())
+	}
+	appScoreboard.StartUnfreezeScheduler()
 }
 
 func prepareControllers() {
 	appUsers = MakeUserController()
+	// Stop workers from previous run before restart
+	appJudgeQueue.Stop()
+	appSlaves.StopHealthCheck()
+	appScoreboard.StopUnfreezeScheduler()
+	appLiveUpdates.Stop()
 	appSlaves = MakeSlaveManager()
+	appJudgeQueue = MakeJudgeQueue(&appSlaves)
+	appScoreboard = MakeScoreboardController()
+	appLiveUpdates = MakeLiveUpdateController()
+	if appConfig.HasFirstSetup {
+		startJudging()
+	}
 	appContestAccess = MakeContestAccessController()
 	appLangPrograms = MakeLanguageProgramController()
-	appScoreboard = MakeScoreboardController()
 	appNotifications = MakeNotificationController()
 }
 
@@ -233,8 +271,11 @@ func prepareHttpEndpoints() {
 	// see dashboard_basic.go
 	r.HandleFunc(FixRootPath("/dashboard"), dashboardHomeGetEndpoint).Methods("GET")
 	r.HandleFunc(FixRootPath("/dashboard/notifications"), dashboardNotificationsEndpoint).Methods("GET")
+	r.HandleFunc(FixRootPath("/dashboard/queueStatus"), dashboardQueueStatusGetEndpoint).Methods("GET")
 	r.HandleFunc(FixRootPath("/dashboard/scoreboard"), dashboardScoreboardsGetEndpoint).Methods("GET")
 	r.HandleFunc(FixRootPath("/dashboard/scoreboard/{id}"), dashboardViewScoreboardGetEndpoint).Methods("GET")
+	r.HandleFunc(FixRootPath("/dashboard/scoreboardExport/{id}/{format}"), dashboardScoreboardExportGetEndpoint).Methods("GET")
+	r.HandleFunc(FixRootPath("/dashboard/liveEvents"), dashboardLiveEventsGetEndpoint).Methods("GET")
 	r.HandleFunc(FixRootPath("/dashboard/profile"), dashboardProfileGetEndpoint).Methods("GET")
 	r.HandleFunc(FixRootPath("/dashboard/profile"), dashboardProfilePostEndpoint).Methods("POST")
 	r.HandleFunc(FixRootPath("/dashboard/settings"), dashboardSettingsGetEndpoint).Methods("GET")
@@ -249,6 +290,17 @@ func prepareHttpEndpoints() {
 	// see dashboard_jury.go
 	r.HandleFunc(FixRootPath("/dashboard/manageContests"), dashboardManageContestsGetEndpoint).Methods("GET")
 	r.HandleFunc(FixRootPath("/dashboard/contestAdd"), dashboardContestAddGetEndpoint).Methods("GET")
+	r.HandleFunc(FixRootPath("/dashboard/rejudge"), dashboardRejudgeGetEndpoint).Methods("GET")
+	r.HandleFunc(FixRootPath("/dashboard/rejudge"), dashboardRejudgePostEndpoint).Methods("POST")
+	r.HandleFunc(FixRootPath("/dashboard/cancelSubmission"), dashboardCancelSubmissionPostEndpoint).Methods("POST")
+	r.HandleFunc(FixRootPath("/dashboard/judgeMismatches"), dashboardJudgeMismatchesGetEndpoint).Methods("GET")
+	r.HandleFunc(FixRootPath("/dashboard/judgeMismatchResolve"), dashboardJudgeMismatchResolvePostEndpoint).Methods("POST")
+	r.HandleFunc(FixRootPath("/dashboard/resolver"), dashboardResolverGetEndpoint).Methods("GET")
+	r.HandleFunc(FixRootPath("/dashboard/resolverReveal"), dashboardResolverRevealPostEndpoint).Methods("POST")
+	r.HandleFunc(FixRootPath("/dashboard/resolverExport"), dashboardResolverExportGetEndpoint).Methods("GET")
+	r.HandleFunc(FixRootPath("/dashboard/resolverUnfreeze"), dashboardResolverUnfreezePostEndpoint).Methods("POST")
+	r.HandleFunc(FixRootPath("/dashboard/rebuildScoreboard"), dashboardRebuildScoreboardGetEndpoint).Methods("GET")
+	r.HandleFunc(FixRootPath("/dashboard/rebuildScoreboard"), dashboardRebuildScoreboardPostEndpoint).Methods("POST")
 	// see dashboard_admin.go
 	r.HandleFunc(FixRootPath("/dashboard/manageUsers"), dashboardManageUsersGetEndpoint).Methods("GET")
 	r.HandleFunc(FixRootPath("/dashboard/userAdd"), dashboardUserAddGetEndpoint).Methods("GET")
@@ -256,9 +308,31 @@ func prepareHttpEndpoints() {
 	r.HandleFunc(FixRootPath("/dashboard/userEdit/{id}"), dashboardUserEditGetEndpoint).Methods("GET")
 	r.HandleFunc(FixRootPath("/dashboard/userEdit"), dashboardUserEditPostEndpoint).Methods("POST")
 	r.HandleFunc(FixRootPath("/dashboard/userDelete/{id}"), dashboardUserDeleteGetEndpoint).Methods("GET")
+	r.HandleFunc(FixRootPath("/dashboard/manageSlaves"), dashboardManageSlavesGetEndpoint).Methods("GET")
+	r.HandleFunc(FixRootPath("/dashboard/slaveRefresh"), dashboardSlaveRefreshGetEndpoint).Methods("GET")
+	r.HandleFunc(FixRootPath("/dashboard/slaveAdd"), dashboardSlaveAddGetEndpoint).Methods("GET")
+	r.HandleFunc(FixRootPath("/dashboard/slaveAdd"), dashboardSlaveAddPostEndpoint).Methods("POST")
+	r.HandleFunc(FixRootPath("/dashboard/slaveEdit/{id}"), dashboardSlaveEditGetEndpoint).Methods("GET")
+	r.HandleFunc(FixRootPath("/dashboard/slaveEdit"), dashboardSlaveEditPostEndpoint).Methods("POST")
+	r.HandleFunc(FixRootPath("/dashboard/slaveToggle/{id}"), dashboardSlaveToggleGetEndpoint).Methods("GET")
+	r.HandleFunc(FixRootPath("/dashboard/slaveDelete/{id}"), dashboardSlaveDeleteGetEndpoint).Methods("GET")
+	r.HandleFunc(FixRootPath("/dashboard/slaveApprove/{id}"), dashboardSlaveApproveGetEndpoint).Methods("GET")
+	// see slaveapi.go
+	r.HandleFunc(FixRootPath(gyrpc.SlaveRegisterPath), slaveApiRegisterPostEndpoint).Methods("POST")
+	r.HandleFunc(FixRootPath(gyrpc.SlaveHeartbeatPath), slaveApiHeartbeatPostEndpoint).Methods("POST")
+	r.HandleFunc(FixRootPath(gyrpc.SlaveDeregisterPath), slaveApiDeregisterPostEndpoint).Methods("POST")
+	// see contestapi.go and contestapifeed.go
+	r.HandleFunc(FixRootPath("/api/contests"), contestApiContestsGetEndpoint).Methods("GET")
+	r.HandleFunc(FixRootPath("/api/contests/{contest}"), contestApiContestGetEndpoint).Methods("GET")
+	r.HandleFunc(FixRootPath("/api/contests/{contest}/scoreboard"), contestApiScoreboardGetEndpoint).Methods("GET")
+	r.HandleFunc(FixRootPath("/api/contests/{contest}/state"), contestApiStateGetEndpoint).Methods("GET")
+	r.HandleFunc(FixRootPath("/api/contests/{contest}/event-feed"), contestApiEventFeedGetEndpoint).Methods("GET")
+	r.HandleFunc(FixRootPath("/api/contests/{contest}/{collection}"), contestApiCollectionGetEndpoint).Methods("GET")
+	r.HandleFunc(FixRootPath("/api/contests/{contest}/{collection}/{item}"), contestApiCollectionGetEndpoint).Methods("GET")
 	// see ajax_users.go
 	r.HandleFunc(FixRootPath("/ajax/getNotifications"), ajaxGetNotifications).Methods("GET")
 	r.HandleFunc(FixRootPath("/ajax/readAllNotifications"), ajaxReadAllNotifications).Methods("GET")
+	r.HandleFunc(FixRootPath("/ajax/getQueueStatus"), ajaxGetQueueStatus).Methods("GET")
 	// see firstsetup.go
 	r.HandleFunc(FixRootPath("/gysetup"), firstSetupGetEndpoint).Methods("GET")
 	r.HandleFunc(FixRootPath("/gysetup"), firstSetupPostEndpoint).Methods("POST")
@@ -346,7 +420,51 @@ func prepareHttpEndpoints() {
 }
 
 // Main Frontend Entry-point
+// Print score differences of contest rebuilt from submissions, then write them if apply set
+func rebuildScoresCommand(contestId int, apply bool) error {
+	prepareConfig()
+	if err := prepareDatabase(); err != nil {
+		return err
+	}
+	if !appConfig.HasFirstSetup {
+		return errors.New("first setup must be done before rebuilding scoreboard")
+	}
+	appScoreboard = MakeScoreboardController()
+	plan, err := appScoreboard.RebuildContestScores(contestId, apply)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Contest %d (%s): %d difference(s)\n", plan.ContestId, plan.ContestName, len(plan.Diffs))
+	for _, diff := range plan.Diffs {
+		board := "private"
+		if diff.Public {
+			board = "public"
+		}
+		fmt.Printf("[%s] %s %s on %s: %s -> %s\n", board, diff.Action, diff.UserName, diff.ProblemName,
+			formatRebuildScore(diff.Current, diff.Action != gytypes.ScoreRebuildInsert),
+			formatRebuildScore(diff.Rebuilt, diff.Action != gytypes.ScoreRebuildDelete))
+	}
+	if apply {
+		fmt.Println("Changes applied")
+	} else if len(plan.Diffs) > 0 {
+		fmt.Println("Nothing written, run again with -apply to write changes")
+	}
+	return nil
+}
+
+func formatRebuildScore(score gytypes.ScoreProblemData, exists bool) string {
+	if !exists {
+		return "(none)"
+	}
+	return fmt.Sprintf("score=%d accepted=%d penalty=%d count=%d regraded=%t", score.Score, score.AcceptedTime,
+		score.PenaltyTime, score.SubmissionCount, score.Regraded)
+}
+
 func main() {
+	rebuildScores := flag.Int("rebuild-scores", 0, "recompute scoreboard of given contest id from submissions and exit")
+	rebuildApply := flag.Bool("apply", false, "write differences found by -rebuild-scores, otherwise only reported")
+	flag.Parse()
+
 	fmt.Printf("Gargoyle Judgement System v%s (Master Server)\n", appVersion)
 	fmt.Println("Copyright (C) Thiekus 2019")
 	fmt.Printf("Built using %s\n", runtime.Version())
@@ -360,6 +478,13 @@ func main() {
 	log := gylib.GetStdLog()
 	log.Printf("ProgramDir: %s", gylib.GetProgramLibDir())
 	log.Printf("WorkDir: %s", gylib.GetWorkDir())
+	if *rebuildScores > 0 {
+		if err := rebuildScoresCommand(*rebuildScores, *rebuildApply); err != nil {
+			log.Error(err)
+			os.Exit(1)
+		}
+		return
+	}
 	for {
 		log.Print("Initializing master server...")
 		// Invalidate maintenance state
@@ -367,7 +492,11 @@ func main() {
 		appOnRestart = false
 		// Prepare now
 		prepareConfig()
-		prepareDatabase()
+		if err := prepareDatabase(); err != nil {
+			// Judging on half migrated schema may corrupt data, fix database then start again
+			log.Error("Server not started, database migration must succeed first")
+			os.Exit(1)
+		}
 		prepareControllers()
 		prepareHttpEndpoints()
 		if !appOnRestart {
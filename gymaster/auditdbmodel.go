@@ -0,0 +1,61 @@
+package main
+
+/* GargoyleJudge - Simple Judgement System for Competitive Programming
+ * Copyright (C) Thiekus 2019
+ * Visit www.khayalan.id for updates
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/. */
+
+import (
+	"time"
+)
+
+// Audited actions
+const (
+	AuditScoreboardUnfreeze = "scoreboard_unfreeze"
+)
+
+type AuditDbModel struct {
+	db DbContext
+}
+
+func NewAuditDbModel(db DbContext) AuditDbModel {
+	adm := AuditDbModel{
+		db: db,
+	}
+	return adm
+}
+
+// Record action done by user, or by system if userId is 0
+func (adm *AuditDbModel) InsertAuditLog(userId int, action string, details string) error {
+	db := adm.db
+	query := `INSERT INTO {{.TablePrefix}}audit_logs (id_user, action, details, create_time) VALUES (?, ?, ?, ?)`
+	stmt, err := db.Prepare(query)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	_, err = stmt.Exec(userId, action, details, time.Now().Unix())
+	return err
+}
+
+// Get time of latest action which details starts with given prefix, zero time if never done
+func (adm *AuditDbModel) GetLastAuditTime(action string, detailsPrefix string) (time.Time, error) {
+	db := adm.db
+	query := `SELECT COALESCE(MAX(create_time), 0) FROM {{.TablePrefix}}audit_logs WHERE (action = ?) AND (details LIKE ?)`
+	stmt, err := db.Prepare(query)
+	if err != nil {
+		return time.Time{}, err
+	}
+	defer stmt.Close()
+	var utTime int64
+	if err = stmt.QueryRow(action, detailsPrefix+"%").Scan(&utTime); err != nil {
+		return time.Time{}, err
+	}
+	if utTime <= 0 {
+		return time.Time{}, nil
+	}
+	return time.Unix(utTime, 0).Local(), nil
+}
@@ -0,0 +1,530 @@
+package main
+
+/* GargoyleJudge - Simple Judgement System for Competitive Programming
+ * Copyright (C) Thiekus 2019
+ * Visit www.khayalan.id for updates
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/. */
+
+// Read-only ICPC Contest API, see https://ccs-specs.icpc.io/contest_api
+// Team ids are user ids and problem ids are problem short names, same as scoreboard export.
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/thiekus/gargoyle-judge/internal/gytypes"
+)
+
+const (
+	ApiCollectionContests       = "contests"
+	ApiCollectionState          = "state"
+	ApiCollectionJudgementTypes = "judgement-types"
+	ApiCollectionProblems       = "problems"
+	ApiCollectionTeams          = "teams"
+	ApiCollectionSubmissions    = "submissions"
+	ApiCollectionJudgements     = "judgements"
+)
+
+type ApiContestData struct {
+	Id                       string  `json:"id"`
+	Name                     string  `json:"name"`
+	FormalName               string  `json:"formal_name"`
+	StartTime                *string `json:"start_time"` // Null for contest without fixed time
+	Duration                 string  `json:"duration"`
+	ScoreboardFreezeDuration *string `json:"scoreboard_freeze_duration"`
+	ScoreboardType           string  `json:"scoreboard_type"`
+	PenaltyTime              int64   `json:"penalty_time"` // in minutes
+}
+
+// Contest state, each time null until reached
+type ApiStateData struct {
+	Started      *string `json:"started"`
+	Frozen       *string `json:"frozen"`
+	Ended        *string `json:"ended"`
+	Thawed       *string `json:"thawed"`
+	Finalized    *string `json:"finalized"`      // Contest ended with every submission judged
+	EndOfUpdates *string `json:"end_of_updates"` // Always null as rejudge still possible
+}
+
+type ApiJudgementTypeData struct {
+	Id      string `json:"id"`
+	Name    string `json:"name"`
+	Penalty bool   `json:"penalty"`
+	Solved  bool   `json:"solved"`
+}
+
+type ApiProblemData struct {
+	Id        string  `json:"id"`
+	Label     string  `json:"label"`
+	Name      string  `json:"name"`
+	Ordinal   int     `json:"ordinal"`
+	Rgb       string  `json:"rgb"`
+	TimeLimit float64 `json:"time_limit"` // in seconds
+}
+
+type ApiTeamData struct {
+	Id          string `json:"id"`
+	Label       string `json:"label"`
+	Name        string `json:"name"`
+	DisplayName string `json:"display_name"`
+	Institution string `json:"institution,omitempty"`
+	CountryCode string `json:"country_code,omitempty"`
+}
+
+type ApiSubmissionData struct {
+	Id          string `json:"id"`
+	LanguageId  string `json:"language_id"`
+	ProblemId   string `json:"problem_id"`
+	TeamId      string `json:"team_id"`
+	Time        string `json:"time"`
+	ContestTime string `json:"contest_time"`
+}
+
+// Judging time not recorded, so judgement times are submission time
+type ApiJudgementData struct {
+	Id               string  `json:"id"`
+	SubmissionId     string  `json:"submission_id"`
+	JudgementTypeId  *string `json:"judgement_type_id"` // Null while still judging
+	Score            *int    `json:"score,omitempty"`   // Only for score based styles
+	StartTime        string  `json:"start_time"`
+	StartContestTime string  `json:"start_contest_time"`
+	EndTime          *string `json:"end_time"`
+	EndContestTime   *string `json:"end_contest_time"`
+}
+
+// Contest API objects of a contest at a time
+type ContestApiSnapshot struct {
+	Contest        ApiContestData
+	State          ApiStateData
+	JudgementTypes []ApiJudgementTypeData
+	Problems       []ApiProblemData
+	Teams          []ApiTeamData
+	Submissions    []ApiSubmissionData
+	Judgements     []ApiJudgementData
+	Scoreboard     gytypes.ScoreboardData
+}
+
+// Judgement types as verdict codes, verdicts not listed here never shown as judgement
+var apiJudgementTypes = map[string]ApiJudgementTypeData{
+	gytypes.SubmissionAccepted:            {"AC", "correct", false, true},
+	gytypes.SubmissionWrongAnswer:         {"WA", "wrong answer", true, false},
+	gytypes.SubmissionPresentationError:   {"PE", "presentation error", true, false},
+	gytypes.SubmissionCompilerError:       {"CE", "compiler error", false, false},
+	gytypes.SubmissionRuntimeError:        {"RTE", "run-time error", true, false},
+	gytypes.SubmissionTimeLimitExceeded:   {"TLE", "time limit exceeded", true, false},
+	gytypes.SubmissionMemoryLimitExceeded: {"MLE", "memory limit exceeded", true, false},
+	gytypes.SubmissionOutputLimitExceeded: {"OLE", "output limit exceeded", true, false},
+	gytypes.SubmissionRestrictedFunction:  {"SV", "security violation", true, false},
+	gytypes.SubmissionCantJudged:          {"CJ", "can't be judged", true, false},
+	gytypes.SubmissionError:               {"JE", "judging error", false, false},
+}
+
+// Format absolute time as Contest API timestamp
+func formatApiTime(t time.Time) string {
+	return t.Format("2006-01-02T15:04:05.000Z07:00")
+}
+
+// Format elapsed seconds as Contest API relative time, e.g 1:05:00.000
+func formatApiRelTime(seconds int64) string {
+	sign := ""
+	if seconds < 0 {
+		sign = "-"
+		seconds = -seconds
+	}
+	return fmt.Sprintf("%s%d:%02d:%02d.000", sign, seconds/3600, (seconds/60)%60, seconds%60)
+}
+
+// Contest object of Contest API, ICPC style scored as pass-fail and others by score
+func makeApiContestData(contest gytypes.ContestData, sci gytypes.ScoreContestInfo) ApiContestData {
+	acd := ApiContestData{
+		Id:             strconv.Itoa(contest.Id),
+		Name:           contest.Title,
+		FormalName:     contest.Title,
+		Duration:       formatApiRelTime(int64(contest.MaxTime)),
+		ScoreboardType: "pass-fail",
+		PenaltyTime:    sci.PenaltyTime / 60,
+	}
+	if sci.Style != gytypes.ScoreStyleICPC {
+		acd.ScoreboardType = "score"
+	}
+	startTime := contest.StartTime.Unix()
+	if startTime > 0 {
+		start := formatApiTime(contest.StartTime)
+		acd.StartTime = &start
+		endTime := contest.EndTime.Unix()
+		if endTime > startTime {
+			acd.Duration = formatApiRelTime(endTime - startTime)
+		}
+		if freezeTime := getApiFreezeTime(contest, sci); freezeTime > 0 {
+			freeze := formatApiRelTime(endTime - freezeTime)
+			acd.ScoreboardFreezeDuration = &freeze
+		}
+	}
+	return acd
+}
+
+// Get time public scoreboard frozen during contest, same as scoreboard cutoff (0 means never frozen)
+func getApiFreezeTime(contest gytypes.ContestData, sci gytypes.ScoreContestInfo) int64 {
+	startTime := contest.StartTime.Unix()
+	endTime := contest.EndTime.Unix()
+	freezeTime := getScoreFreezeTime(sci)
+	if (startTime <= 0) || (freezeTime <= 0) || (freezeTime >= endTime) {
+		return 0
+	}
+	// Freeze set before start hides whole contest
+	if freezeTime < startTime {
+		return startTime
+	}
+	return freezeTime
+}
+
+// State object at given time, thawTime is zero if never unfrozen and judged tells all submissions judged
+func makeApiStateData(contest gytypes.ContestData, sci gytypes.ScoreContestInfo, now time.Time,
+	thawTime time.Time, judged bool) ApiStateData {
+	var asd ApiStateData
+	reached := func(t int64) *string {
+		if (t <= 0) || (t > now.Unix()) {
+			return nil
+		}
+		s := formatApiTime(time.Unix(t, 0).Local())
+		return &s
+	}
+	startTime := contest.StartTime.Unix()
+	if startTime <= 0 {
+		return asd
+	}
+	asd.Started = reached(startTime)
+	asd.Frozen = reached(getApiFreezeTime(contest, sci))
+	endTime := contest.EndTime.Unix()
+	if endTime > startTime {
+		asd.Ended = reached(endTime)
+	}
+	if sci.Unfrozen && (asd.Frozen != nil) {
+		// Unfrozen before audit log kept, configured time or contest end is best known
+		if thawTime.IsZero() {
+			thawTime = sci.UnfreezeTime
+			if (thawTime.Unix() <= 0) || thawTime.After(now) {
+				thawTime = contest.EndTime
+			}
+		}
+		asd.Thawed = reached(thawTime.Unix())
+	}
+	if (asd.Ended != nil) && judged {
+		asd.Finalized = asd.Ended
+	}
+	return asd
+}
+
+// Build Contest API objects from contest, submission and score models, always as seen by jury
+func GetContestApiSnapshot(contestId int) (*ContestApiSnapshot, error) {
+	db, err := OpenDatabase()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+	cdm := NewContestDbModel(db)
+	contest, err := cdm.GetContestDetails(contestId)
+	if err != nil {
+		return nil, err
+	}
+	sdm := NewScoreDbModel(db)
+	sci, err := sdm.GetContestInfoById(contestId)
+	if err != nil {
+		return nil, err
+	}
+	snap := ContestApiSnapshot{
+		JudgementTypes: []ApiJudgementTypeData{},
+		Problems:       []ApiProblemData{},
+		Teams:          []ApiTeamData{},
+		Submissions:    []ApiSubmissionData{},
+		Judgements:     []ApiJudgementData{},
+	}
+	// Relative times counted from contest start, or zero for contest without fixed time
+	startTime := contest.StartTime.Unix()
+	contestTime := func(t time.Time) string {
+		if startTime <= 0 {
+			return formatApiRelTime(0)
+		}
+		return formatApiRelTime(t.Unix() - startTime)
+	}
+	snap.Contest = makeApiContestData(contest, sci)
+	judged := true
+	var verdicts []string
+	for verdict := range apiJudgementTypes {
+		verdicts = append(verdicts, verdict)
+	}
+	sort.Strings(verdicts)
+	for _, verdict := range verdicts {
+		snap.JudgementTypes = append(snap.JudgementTypes, apiJudgementTypes[verdict])
+	}
+	// Same order and colors as scoreboard
+	problems, err := cdm.GetProblemSet(contestId)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(problems, func(i, j int) bool {
+		return problems[i].ShortName < problems[j].ShortName
+	})
+	colors := appScoreboard.GetColorWheel(len(problems))
+	problemIds := make(map[int]string)
+	for i, prob := range problems {
+		problemIds[prob.Id] = prob.ShortName
+		snap.Problems = append(snap.Problems, ApiProblemData{
+			Id:        prob.ShortName,
+			Label:     prob.ShortName,
+			Name:      prob.Name,
+			Ordinal:   i,
+			Rgb:       colors[i],
+			TimeLimit: float64(prob.TimeLimit) / 1000,
+		})
+	}
+	users, err := cdm.GetContestantsOfContest(contestId)
+	if err != nil {
+		return nil, err
+	}
+	teamIds := make(map[int]bool)
+	for _, user := range users {
+		teamIds[user.Id] = true
+		snap.Teams = append(snap.Teams, ApiTeamData{
+			Id:          strconv.Itoa(user.Id),
+			Label:       user.Username,
+			Name:        user.DisplayName,
+			DisplayName: user.DisplayName,
+			Institution: user.Institution,
+			CountryCode: user.CountryId,
+		})
+	}
+	subm := NewSubmissionDbModel(db)
+	subs, err := subm.GetSubmissionsOfContest(contestId)
+	if err != nil {
+		return nil, err
+	}
+	for _, sub := range subs {
+		// Jury test runs and cancelled ones never shown on scoreboard
+		if !teamIds[sub.UserId] || (sub.JudgeState == gytypes.JudgeStateCancelled) {
+			continue
+		}
+		subId := strconv.Itoa(sub.Id)
+		langId := strconv.Itoa(sub.LanguageId)
+		if lang, err := appLangPrograms.GetLanguageFromId(sub.LanguageId); err == nil {
+			langId = lang.ExtensionName
+		}
+		snap.Submissions = append(snap.Submissions, ApiSubmissionData{
+			Id:          subId,
+			LanguageId:  langId,
+			ProblemId:   problemIds[sub.ProblemId],
+			TeamId:      strconv.Itoa(sub.UserId),
+			Time:        formatApiTime(sub.SubmitTime),
+			ContestTime: contestTime(sub.SubmitTime),
+		})
+		if sub.JudgeState != gytypes.JudgeStateDone {
+			judged = false
+		}
+		if sub.JudgeState == gytypes.JudgeStateQueued {
+			continue
+		}
+		judgement := ApiJudgementData{
+			Id:               subId,
+			SubmissionId:     subId,
+			StartTime:        formatApiTime(sub.SubmitTime),
+			StartContestTime: contestTime(sub.SubmitTime),
+		}
+		if jt, exists := apiJudgementTypes[sub.Verdict]; exists && (sub.JudgeState == gytypes.JudgeStateDone) {
+			endTime := judgement.StartTime
+			endContestTime := judgement.StartContestTime
+			judgement.JudgementTypeId = &jt.Id
+			judgement.EndTime = &endTime
+			judgement.EndContestTime = &endContestTime
+			if snap.Contest.ScoreboardType == "score" {
+				score := sub.Score
+				judgement.Score = &score
+			}
+		}
+		snap.Judgements = append(snap.Judgements, judgement)
+	}
+	var thawTime time.Time
+	if sci.Unfrozen {
+		adm := NewAuditDbModel(db)
+		if thawTime, err = adm.GetLastAuditTime(AuditScoreboardUnfreeze, getUnfreezeAuditPrefix(contestId)); err != nil {
+			return nil, err
+		}
+	}
+	snap.State = makeApiStateData(contest, sci, time.Now(), thawTime, judged)
+	snap.Scoreboard, err = appScoreboard.GetPrivateScoreboard(contestId)
+	if err != nil {
+		return nil, err
+	}
+	return &snap, nil
+}
+
+// Get objects of a collection with their ids, in order listed by API
+func (cs *ContestApiSnapshot) collection(name string) ([]interface{}, []string, bool) {
+	var items []interface{}
+	var ids []string
+	switch name {
+	case ApiCollectionContests:
+		items, ids = append(items, cs.Contest), append(ids, cs.Contest.Id)
+	case ApiCollectionState:
+		items, ids = append(items, cs.State), append(ids, cs.Contest.Id)
+	case ApiCollectionJudgementTypes:
+		for _, v := range cs.JudgementTypes {
+			items, ids = append(items, v), append(ids, v.Id)
+		}
+	case ApiCollectionProblems:
+		for _, v := range cs.Problems {
+			items, ids = append(items, v), append(ids, v.Id)
+		}
+	case ApiCollectionTeams:
+		for _, v := range cs.Teams {
+			items, ids = append(items, v), append(ids, v.Id)
+		}
+	case ApiCollectionSubmissions:
+		for _, v := range cs.Submissions {
+			items, ids = append(items, v), append(ids, v.Id)
+		}
+	case ApiCollectionJudgements:
+		for _, v := range cs.Judgements {
+			items, ids = append(items, v), append(ids, v.Id)
+		}
+	default:
+		return nil, nil, false
+	}
+	if items == nil {
+		items = []interface{}{}
+	}
+	return items, ids, true
+}
+
+// Get user allowed to use Contest API, by login session or HTTP basic auth, returns nil if response already written
+func getContestApiUser(w http.ResponseWriter, r *http.Request) *gytypes.UserInfo {
+	ui := appUsers.GetLoggedUserInfo(r)
+	if ui == nil {
+		if username, password, ok := r.BasicAuth(); ok {
+			if db, err := OpenDatabase(); err == nil {
+				udm := NewUserDbModel(db)
+				if user, err := udm.GetUserByLogin(username, password); err == nil {
+					ui = &user
+				}
+				db.Close()
+			}
+		}
+	}
+	if ui == nil {
+		w.Header().Set("WWW-Authenticate", `Basic realm="Contest API"`)
+		http.Error(w, "401 Unauthorized", http.StatusUnauthorized)
+		return nil
+	}
+	// Everything shown as jury sees, including frozen submissions
+	if !ui.Active || ui.Banned || !(ui.IsAdmin() || ui.IsJury()) {
+		http.Error(w, "403 Forbidden", http.StatusForbidden)
+		return nil
+	}
+	return ui
+}
+
+func writeContestApiJson(w http.ResponseWriter, v interface{}) {
+	if data, err := json.Marshal(v); err == nil {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.Write(data)
+	} else {
+		http.Error(w, "500 Internal Server Error: "+err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// Get snapshot of contest in request path, returns nil if response already written
+func loadContestApiSnapshot(w http.ResponseWriter, r *http.Request) *ContestApiSnapshot {
+	if getContestApiUser(w, r) == nil {
+		return nil
+	}
+	contestId, err := strconv.Atoi(mux.Vars(r)["contest"])
+	if err != nil {
+		http.Error(w, "404 Not Found", http.StatusNotFound)
+		return nil
+	}
+	snap, err := GetContestApiSnapshot(contestId)
+	if err != nil {
+		http.Error(w, "404 Not Found: "+err.Error(), http.StatusNotFound)
+		return nil
+	}
+	return snap
+}
+
+func contestApiContestsGetEndpoint(w http.ResponseWriter, r *http.Request) {
+	if getContestApiUser(w, r) == nil {
+		return
+	}
+	db, err := OpenDatabase()
+	if err != nil {
+		http.Error(w, "500 Internal Server Error: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+	cdm := NewContestDbModel(db)
+	contests, err := cdm.GetContestList()
+	if err != nil {
+		http.Error(w, "500 Internal Server Error: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	sdm := NewScoreDbModel(db)
+	list := []ApiContestData{}
+	for _, contest := range contests {
+		sci, err := sdm.GetContestInfoById(contest.Id)
+		if err != nil {
+			http.Error(w, "500 Internal Server Error: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		list = append(list, makeApiContestData(contest, sci))
+	}
+	writeContestApiJson(w, list)
+}
+
+func contestApiContestGetEndpoint(w http.ResponseWriter, r *http.Request) {
+	if snap := loadContestApiSnapshot(w, r); snap != nil {
+		writeContestApiJson(w, snap.Contest)
+	}
+}
+
+func contestApiStateGetEndpoint(w http.ResponseWriter, r *http.Request) {
+	if snap := loadContestApiSnapshot(w, r); snap != nil {
+		writeContestApiJson(w, snap.State)
+	}
+}
+
+func contestApiCollectionGetEndpoint(w http.ResponseWriter, r *http.Request) {
+	snap := loadContestApiSnapshot(w, r)
+	if snap == nil {
+		return
+	}
+	vars := mux.Vars(r)
+	items, ids, ok := snap.collection(vars["collection"])
+	if !ok || (vars["collection"] == ApiCollectionContests) || (vars["collection"] == ApiCollectionState) {
+		http.Error(w, "404 Not Found", http.StatusNotFound)
+		return
+	}
+	itemId, single := vars["item"]
+	if !single {
+		writeContestApiJson(w, items)
+		return
+	}
+	for i, id := range ids {
+		if id == itemId {
+			writeContestApiJson(w, items[i])
+			return
+		}
+	}
+	http.Error(w, "404 Not Found", http.StatusNotFound)
+}
+
+func contestApiScoreboardGetEndpoint(w http.ResponseWriter, r *http.Request) {
+	if snap := loadContestApiSnapshot(w, r); snap != nil {
+		writeContestApiJson(w, MakeIcpcScoreboard(snap.Scoreboard))
+	}
+}
@@ -0,0 +1,93 @@
+package main
+
+/* GargoyleJudge - Simple Judgement System for Competitive Programming
+ * Copyright (C) Thiekus 2019
+ * Visit www.khayalan.id for updates
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/. */
+
+import (
+	"testing"
+	"time"
+
+	"github.com/thiekus/gargoyle-judge/internal/gytypes"
+)
+
+// Five hours contest frozen on last hour
+func makeTestApiContest(freezeMinutes int64, enableFreeze bool) (gytypes.ContestData, gytypes.ScoreContestInfo) {
+	contest := gytypes.ContestData{
+		StartTime: time.Unix(testContestStart, 0),
+		EndTime:   time.Unix(testContestStart+5*3600, 0),
+	}
+	sci := gytypes.ScoreContestInfo{
+		Style:        gytypes.ScoreStyleICPC,
+		EnableFreeze: enableFreeze,
+		FreezeTime:   time.Unix(testContestStart+freezeMinutes*60, 0),
+	}
+	return contest, sci
+}
+
+// Freeze duration must tell same freeze as public scoreboard cutoff
+func TestApiFreezeDuration(t *testing.T) {
+	tests := []struct {
+		name          string
+		freezeMinutes int64
+		enableFreeze  bool
+		want          string // Empty if not frozen
+	}{
+		{"enabled", 240, true, "1:00:00.000"},
+		{"not enabled still cut", 240, false, "1:00:00.000"},
+		{"before start", -60, true, "5:00:00.000"},
+		{"after end", 360, true, ""},
+	}
+	sdm := ScoreDbModel{}
+	for _, tt := range tests {
+		contest, sci := makeTestApiContest(tt.freezeMinutes, tt.enableFreeze)
+		acd := makeApiContestData(contest, sci)
+		got := ""
+		if acd.ScoreboardFreezeDuration != nil {
+			got = *acd.ScoreboardFreezeDuration
+		}
+		if got != tt.want {
+			t.Errorf("%s: freeze duration = %q, want %q", tt.name, got, tt.want)
+		}
+		if (tt.want != "") && (sdm.getScoreCutoff(sci, true) <= 0) {
+			t.Errorf("%s: public scoreboard not cut while frozen", tt.name)
+		}
+	}
+}
+
+func TestMakeApiStateData(t *testing.T) {
+	contest, sci := makeTestApiContest(240, false)
+	at := func(minutes int64) time.Time {
+		return time.Unix(testContestStart+minutes*60, 0)
+	}
+	tests := []struct {
+		name     string
+		now      int64
+		unfrozen bool
+		judged   bool
+		want     [5]bool // Started, frozen, ended, thawed, finalized
+	}{
+		{"before start", -10, false, true, [5]bool{}},
+		{"running", 60, false, true, [5]bool{true}},
+		{"frozen", 250, false, true, [5]bool{true, true}},
+		{"ended while judging", 310, false, false, [5]bool{true, true, true}},
+		{"finalized", 320, false, true, [5]bool{true, true, true, false, true}},
+		{"thawed", 400, true, true, [5]bool{true, true, true, true, true}},
+	}
+	for _, tt := range tests {
+		sci.Unfrozen = tt.unfrozen
+		var thawTime time.Time
+		if tt.unfrozen {
+			thawTime = at(tt.now)
+		}
+		asd := makeApiStateData(contest, sci, at(tt.now), thawTime, tt.judged)
+		got := [5]bool{asd.Started != nil, asd.Frozen != nil, asd.Ended != nil, asd.Thawed != nil, asd.Finalized != nil}
+		if got != tt.want {
+			t.Errorf("%s: state reached = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,180 @@
+package main
+
+/* GargoyleJudge - Simple Judgement System for Competitive Programming
+ * Copyright (C) Thiekus 2019
+ * Visit www.khayalan.id for updates
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/. */
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/thiekus/gargoyle-judge/internal/gylib"
+)
+
+const (
+	ApiEventCreate = "create"
+	ApiEventUpdate = "update"
+	ApiEventDelete = "delete"
+)
+
+const (
+	contestApiFeedPoll      = 5 * time.Second   // Interval of checking changes while streaming
+	contestApiFeedKeepAlive = 120 * time.Second // Newline sent if nothing happened, as required by spec
+)
+
+// Collections in order of their events, so referenced objects always created first
+var contestApiFeedCollections = []string{
+	ApiCollectionContests,
+	ApiCollectionState,
+	ApiCollectionJudgementTypes,
+	ApiCollectionProblems,
+	ApiCollectionTeams,
+	ApiCollectionSubmissions,
+	ApiCollectionJudgements,
+}
+
+type ApiEventData struct {
+	Type string      `json:"type"`
+	Id   string      `json:"id"`
+	Op   string      `json:"op"`
+	Data interface{} `json:"data"`
+}
+
+// Objects already sent to a feed client, as encoded on last event of each collection
+type ContestApiFeedState struct {
+	lastEventId int
+	sent        map[string]map[string][]byte
+	sentOrder   map[string][]string
+}
+
+func MakeContestApiFeedState() ContestApiFeedState {
+	return ContestApiFeedState{
+		sent:      make(map[string]map[string][]byte),
+		sentOrder: make(map[string][]string),
+	}
+}
+
+func (fs *ContestApiFeedState) makeEvent(collection, op string, data interface{}) ApiEventData {
+	fs.lastEventId++
+	return ApiEventData{
+		Type: collection,
+		Id:   strconv.Itoa(fs.lastEventId),
+		Op:   op,
+		Data: data,
+	}
+}
+
+// Get events turning previously sent objects into those of snapshot
+func (fs *ContestApiFeedState) diffSnapshot(snap *ContestApiSnapshot) ([]ApiEventData, error) {
+	var events []ApiEventData
+	for _, collection := range contestApiFeedCollections {
+		items, ids, _ := snap.collection(collection)
+		sent := fs.sent[collection]
+		if sent == nil {
+			sent = make(map[string][]byte)
+			fs.sent[collection] = sent
+		}
+		current := make(map[string]bool)
+		var order []string
+		for i, item := range items {
+			data, err := json.Marshal(item)
+			if err != nil {
+				return nil, err
+			}
+			id := ids[i]
+			current[id] = true
+			order = append(order, id)
+			if prev, exists := sent[id]; !exists {
+				events = append(events, fs.makeEvent(collection, ApiEventCreate, item))
+			} else if !bytes.Equal(prev, data) {
+				events = append(events, fs.makeEvent(collection, ApiEventUpdate, item))
+			}
+			sent[id] = data
+		}
+		for _, id := range fs.sentOrder[collection] {
+			if !current[id] {
+				events = append(events, fs.makeEvent(collection, ApiEventDelete, map[string]string{"id": id}))
+				delete(sent, id)
+			}
+		}
+		fs.sentOrder[collection] = order
+	}
+	return events, nil
+}
+
+// NDJSON event feed, keeps streaming changes unless stream=false given
+func contestApiEventFeedGetEndpoint(w http.ResponseWriter, r *http.Request) {
+	snap := loadContestApiSnapshot(w, r)
+	if snap == nil {
+		return
+	}
+	log := gylib.GetStdLog()
+	// Content type must be set before writing, so compression handler never buffers it
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.Header().Set("Cache-Control", "no-cache")
+	flusher, _ := w.(http.Flusher)
+	encoder := json.NewEncoder(w)
+	state := MakeContestApiFeedState()
+	writeEvents := func(snap *ContestApiSnapshot) error {
+		events, err := state.diffSnapshot(snap)
+		if err != nil {
+			return err
+		}
+		for _, event := range events {
+			if err = encoder.Encode(event); err != nil {
+				return err
+			}
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+		return nil
+	}
+	if err := writeEvents(snap); err != nil {
+		log.Error(err)
+		return
+	}
+	if r.URL.Query().Get("stream") == "false" {
+		return
+	}
+	contestId, _ := strconv.Atoi(snap.Contest.Id)
+	ticker := time.NewTicker(contestApiFeedPoll)
+	defer ticker.Stop()
+	lastWrite := time.Now()
+	lastEventId := state.lastEventId
+	for !appOnShutdown {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-ticker.C:
+		}
+		snap, err := GetContestApiSnapshot(contestId)
+		if err != nil {
+			log.Error(err)
+			return
+		}
+		if err = writeEvents(snap); err != nil {
+			// Client likely gone
+			return
+		}
+		if state.lastEventId != lastEventId {
+			lastEventId = state.lastEventId
+			lastWrite = time.Now()
+		} else if time.Since(lastWrite) >= contestApiFeedKeepAlive {
+			if _, err = w.Write([]byte("\n")); err != nil {
+				return
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+			lastWrite = time.Now()
+		}
+	}
+}
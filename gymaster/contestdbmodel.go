@@ -296,6 +296,7 @@ func (cdm *ContestDbModel) GetProblemSet(contestId int) ([]gytypes.ProblemData,
 			return qs, err
 		}
 		qd.ProblemUrl = "dashboard/problem/" + strconv.Itoa(qd.Id)
+		qd.TimeLimitStr = gytypes.FormatTimeLimit(qd.TimeLimit)
 		qs = append(qs, qd)
 	}
 	return qs, nil
@@ -326,6 +327,7 @@ func (cdm *ContestDbModel) GetProblemById(problemId int) (gytypes.ProblemData, e
 		return qd, err
 	}
 	qd.ContestUrl = "dashboard/problemSet/" + strconv.Itoa(qd.ContestId)
+	qd.TimeLimitStr = gytypes.FormatTimeLimit(qd.TimeLimit)
 	return qd, nil
 }
 
@@ -352,3 +354,36 @@ func (cdm *ContestDbModel) InsertContestAccess(access gytypes.ContestAccess) err
 	}
 	return nil
 }
+
+// Get users having access to contest, only basic profile filled
+func (cdm *ContestDbModel) GetContestantsOfContest(contestId int) ([]gytypes.UserInfo, error) {
+	db := cdm.db
+	query := `SELECT u.id, u.username, u.display_name, u.institution, u.country_id FROM {{.TablePrefix}}contest_access as a
+        INNER JOIN {{.TablePrefix}}users as u ON a.id_user = u.id WHERE a.id_contest = ? ORDER BY u.id ASC`
+	stmt, err := db.Prepare(query)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+	rows, err := stmt.Query(contestId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var users []gytypes.UserInfo
+	for rows.Next() {
+		ui := gytypes.UserInfo{}
+		err = rows.Scan(
+			&ui.Id,
+			&ui.Username,
+			&ui.DisplayName,
+			&ui.Institution,
+			&ui.CountryId,
+		)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, ui)
+	}
+	return users, nil
+}
@@ -0,0 +1,79 @@
+package main
+
+/* GargoyleJudge - Simple Judgement System for Competitive Programming
+ * Copyright (C) Thiekus 2019
+ * Visit www.khayalan.id for updates
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/. */
+
+import (
+	"errors"
+	"sort"
+
+	"github.com/thiekus/gargoyle-judge/internal/gytypes"
+)
+
+// Scoring rules of a contest style, scoreboard only handles what's common for all styles
+type ContestStyle interface {
+	// Compute problem score from judged submissions, which already filtered by verdict and cutoff time
+	AccumulateScore(sci gytypes.ScoreContestInfo, score *gytypes.ScoreProblemData, history []gytypes.ScoreSubmissionData)
+	// Whether problem score considered as solved
+	IsSolved(score gytypes.ScoreProblemData) bool
+	// Whether scoreboard shows penalty column
+	HasPenalty() bool
+	// Total penalty of contestant, problem accepted times already relative to contest start
+	ContestantPenalty(sci gytypes.ScoreContestInfo, user gytypes.ScoreContestantData) int64
+	// Negative if a ranked above b, zero if tied
+	CompareContestants(a, b gytypes.ScoreContestantData) int64
+	// Whether tied contestants share same rank number
+	SharedRank() bool
+	// Text shown on scoreboard cell of attempted problem
+	ProblemCell(score gytypes.ScoreProblemData) string
+}
+
+// Get scoring rules by contest style name
+func GetContestStyle(name string) (ContestStyle, error) {
+	switch name {
+	case gytypes.ScoreStyleICPC:
+		return icpcContestStyle{}, nil
+	case gytypes.ScoreStyleIOI:
+		return ioiContestStyle{tieBreakers: appConfig.IOITieBreakers}, nil
+	case gytypes.ScoreStyleAtCoder:
+		return atcoderContestStyle{}, nil
+	case gytypes.ScoreStyleCodeforces:
+		return codeforcesContestStyle{}, nil
+	}
+	return nil, errors.New("unsupported contest style")
+}
+
+// Sort contestants and assign rank number as contest style rules
+func rankContestants(contestants gytypes.ScoreContestantDataList, style ContestStyle) {
+	sort.SliceStable(contestants, func(i, j int) bool {
+		if cmp := style.CompareContestants(contestants[i], contestants[j]); cmp != 0 {
+			return cmp < 0
+		}
+		// Keep tied contestants in stable order between refreshes
+		return contestants[i].UserId < contestants[j].UserId
+	})
+	for i := range contestants {
+		if (i > 0) && style.SharedRank() && (style.CompareContestants(contestants[i-1], contestants[i]) == 0) {
+			contestants[i].RankNumber = contestants[i-1].RankNumber
+		} else {
+			contestants[i].RankNumber = i + 1
+		}
+	}
+}
+
+// Best score reached over submissions, stored with time it first reached
+func accumulateBestScore(score *gytypes.ScoreProblemData, sub gytypes.ScoreSubmissionData) bool {
+	score.SubmissionCount++
+	score.Regraded = score.Regraded || sub.Regraded
+	if sub.Score > score.Score {
+		score.Score = sub.Score
+		score.AcceptedTime = sub.SubmitTime
+		return true
+	}
+	return false
+}
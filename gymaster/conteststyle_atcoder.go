@@ -0,0 +1,67 @@
+package main
+
+/* GargoyleJudge - Simple Judgement System for Competitive Programming
+ * Copyright (C) Thiekus 2019
+ * Visit www.khayalan.id for updates
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/. */
+
+import (
+	"fmt"
+
+	"github.com/thiekus/gargoyle-judge/internal/gytypes"
+)
+
+// Best score of each problem, ties broken by time of last score improvement
+// plus penalty of rejected attempts before each improvement
+type atcoderContestStyle struct{}
+
+func (atcoderContestStyle) AccumulateScore(sci gytypes.ScoreContestInfo, score *gytypes.ScoreProblemData,
+	history []gytypes.ScoreSubmissionData) {
+	pending := int64(0)
+	for _, sub := range history {
+		if accumulateBestScore(score, sub) {
+			score.PenaltyTime += pending
+			pending = 0
+		} else if sub.Verdict != gytypes.SubmissionAccepted {
+			// Only counted if followed by improvement
+			pending += sci.PenaltyTime
+		}
+	}
+}
+
+func (atcoderContestStyle) IsSolved(score gytypes.ScoreProblemData) bool {
+	return score.Score >= gytypes.ScoreFullPoints
+}
+
+func (atcoderContestStyle) HasPenalty() bool {
+	return true
+}
+
+func (atcoderContestStyle) ContestantPenalty(sci gytypes.ScoreContestInfo, user gytypes.ScoreContestantData) int64 {
+	penalty := user.LastScoreTime
+	for _, score := range user.Problems {
+		penalty += score.PenaltyTime
+	}
+	return penalty
+}
+
+func (atcoderContestStyle) CompareContestants(a, b gytypes.ScoreContestantData) int64 {
+	if a.TotalScore != b.TotalScore {
+		return int64(b.TotalScore - a.TotalScore)
+	}
+	return a.TotalPenaltyTime - b.TotalPenaltyTime
+}
+
+func (atcoderContestStyle) SharedRank() bool {
+	return true
+}
+
+func (atcoderContestStyle) ProblemCell(score gytypes.ScoreProblemData) string {
+	if score.Score > 0 {
+		return fmt.Sprintf("%d (%s)", score.Score, score.AcceptedTimeStr)
+	}
+	return "0"
+}
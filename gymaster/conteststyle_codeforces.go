@@ -0,0 +1,82 @@
+package main
+
+/* GargoyleJudge - Simple Judgement System for Competitive Programming
+ * Copyright (C) Thiekus 2019
+ * Visit www.khayalan.id for updates
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/. */
+
+import (
+	"strconv"
+
+	"github.com/thiekus/gargoyle-judge/internal/gytypes"
+)
+
+const (
+	codeforcesDecayMinutes = 250 // Minutes until points decayed completely, before floored
+	codeforcesMinRatio     = 0.3 // Points never decayed below this fraction
+	codeforcesWrongRatio   = 0.1 // Fraction of points lost for each rejected attempt before
+)
+
+// Points of each problem decayed by elapsed contest time and rejected attempts
+type codeforcesContestStyle struct{}
+
+// Decayed points of submission, no decay on contest without fixed start time
+func (codeforcesContestStyle) decayedPoints(sci gytypes.ScoreContestInfo, sub gytypes.ScoreSubmissionData,
+	wrong int) int {
+	value := float64(sub.Score)
+	minutes := float64(0)
+	if start := sci.StartTimestamp.Unix(); (start > 0) && (sub.SubmitTime > start) {
+		minutes = float64(sub.SubmitTime-start) / 60
+	}
+	points := value*(1-minutes/codeforcesDecayMinutes) - value*codeforcesWrongRatio*float64(wrong)
+	if floor := value * codeforcesMinRatio; points < floor {
+		points = floor
+	}
+	return int(points)
+}
+
+func (cs codeforcesContestStyle) AccumulateScore(sci gytypes.ScoreContestInfo, score *gytypes.ScoreProblemData,
+	history []gytypes.ScoreSubmissionData) {
+	wrong := 0
+	for _, sub := range history {
+		score.SubmissionCount++
+		score.Regraded = score.Regraded || sub.Regraded
+		if sub.Score > 0 {
+			if points := cs.decayedPoints(sci, sub, wrong); points > score.Score {
+				score.Score = points
+			}
+		}
+		if sub.Verdict != gytypes.SubmissionAccepted {
+			wrong++
+		} else if score.AcceptedTime == 0 {
+			score.AcceptedTime = sub.SubmitTime
+		}
+	}
+}
+
+func (codeforcesContestStyle) IsSolved(score gytypes.ScoreProblemData) bool {
+	return score.AcceptedTime > 0
+}
+
+func (codeforcesContestStyle) HasPenalty() bool {
+	return false
+}
+
+func (codeforcesContestStyle) ContestantPenalty(sci gytypes.ScoreContestInfo, user gytypes.ScoreContestantData) int64 {
+	return 0
+}
+
+func (codeforcesContestStyle) CompareContestants(a, b gytypes.ScoreContestantData) int64 {
+	return int64(b.TotalScore - a.TotalScore)
+}
+
+func (codeforcesContestStyle) SharedRank() bool {
+	return true
+}
+
+func (codeforcesContestStyle) ProblemCell(score gytypes.ScoreProblemData) string {
+	return strconv.Itoa(score.Score)
+}
@@ -0,0 +1,73 @@
+package main
+
+/* GargoyleJudge - Simple Judgement System for Competitive Programming
+ * Copyright (C) Thiekus 2019
+ * Visit www.khayalan.id for updates
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/. */
+
+import (
+	"github.com/thiekus/gargoyle-judge/internal/gytypes"
+)
+
+// Solved problem count first, then penalty time from wrong attempts and accepted time
+type icpcContestStyle struct{}
+
+func (icpcContestStyle) AccumulateScore(sci gytypes.ScoreContestInfo, score *gytypes.ScoreProblemData,
+	history []gytypes.ScoreSubmissionData) {
+	for _, sub := range history {
+		score.SubmissionCount++
+		score.Regraded = score.Regraded || sub.Regraded
+		// Since ICPC doesn't matter what you write out
+		score.Score = 0
+		if sub.Score >= gytypes.ScoreFullPoints {
+			score.Score = 1
+		}
+		if sub.Verdict == gytypes.SubmissionAccepted {
+			score.AcceptedTime = sub.SubmitTime
+			// Ignore any submission after problem was solved
+			break
+		}
+		score.PenaltyTime += sci.PenaltyTime
+	}
+}
+
+func (icpcContestStyle) IsSolved(score gytypes.ScoreProblemData) bool {
+	return score.AcceptedTime > 0
+}
+
+func (icpcContestStyle) HasPenalty() bool {
+	return true
+}
+
+func (icpcContestStyle) ContestantPenalty(sci gytypes.ScoreContestInfo, user gytypes.ScoreContestantData) int64 {
+	penalty := int64(0)
+	for _, score := range user.Problems {
+		penalty += score.PenaltyTime
+		if score.AcceptedTime > 0 {
+			penalty += score.AcceptedTime
+		}
+	}
+	return penalty
+}
+
+func (icpcContestStyle) CompareContestants(a, b gytypes.ScoreContestantData) int64 {
+	if a.TotalScore != b.TotalScore {
+		return int64(b.TotalScore - a.TotalScore)
+	}
+	// Bigger penalty, then lower rank for same score
+	return a.TotalPenaltyTime - b.TotalPenaltyTime
+}
+
+func (icpcContestStyle) SharedRank() bool {
+	return false
+}
+
+func (icpcContestStyle) ProblemCell(score gytypes.ScoreProblemData) string {
+	if score.IsAccepted {
+		return score.AcceptedTimeStr
+	}
+	return ""
+}
@@ -0,0 +1,69 @@
+package main
+
+/* GargoyleJudge - Simple Judgement System for Competitive Programming
+ * Copyright (C) Thiekus 2019
+ * Visit www.khayalan.id for updates
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/. */
+
+import (
+	"strconv"
+
+	"github.com/thiekus/gargoyle-judge/internal/gytypes"
+)
+
+// Best score of each problem summed without any penalty, ties separated by configured tie-breakers
+type ioiContestStyle struct {
+	tieBreakers []string
+}
+
+func (ioiContestStyle) AccumulateScore(sci gytypes.ScoreContestInfo, score *gytypes.ScoreProblemData,
+	history []gytypes.ScoreSubmissionData) {
+	// Worse later submission never lowers score
+	for _, sub := range history {
+		accumulateBestScore(score, sub)
+	}
+}
+
+func (ioiContestStyle) IsSolved(score gytypes.ScoreProblemData) bool {
+	return score.Score >= gytypes.ScoreFullPoints
+}
+
+func (ioiContestStyle) HasPenalty() bool {
+	return false
+}
+
+func (ioiContestStyle) ContestantPenalty(sci gytypes.ScoreContestInfo, user gytypes.ScoreContestantData) int64 {
+	return 0
+}
+
+func (cs ioiContestStyle) CompareContestants(a, b gytypes.ScoreContestantData) int64 {
+	if a.TotalScore != b.TotalScore {
+		return int64(b.TotalScore - a.TotalScore)
+	}
+	for _, tb := range cs.tieBreakers {
+		var diff int64
+		switch tb {
+		case gytypes.ScoreTieBreakSolved:
+			diff = int64(b.SolvedCount - a.SolvedCount)
+		case gytypes.ScoreTieBreakTime:
+			diff = a.LastScoreTime - b.LastScoreTime
+		case gytypes.ScoreTieBreakAttempts:
+			diff = int64(a.AttemptCount - b.AttemptCount)
+		}
+		if diff != 0 {
+			return diff
+		}
+	}
+	return 0
+}
+
+func (ioiContestStyle) SharedRank() bool {
+	return true
+}
+
+func (ioiContestStyle) ProblemCell(score gytypes.ScoreProblemData) string {
+	return strconv.Itoa(score.Score)
+}
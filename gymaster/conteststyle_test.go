@@ -0,0 +1,213 @@
+package main
+
+/* GargoyleJudge - Simple Judgement System for Competitive Programming
+ * Copyright (C) Thiekus 2019
+ * Visit www.khayalan.id for updates
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/. */
+
+import (
+	"testing"
+	"time"
+
+	"github.com/thiekus/gargoyle-judge/internal/gytypes"
+)
+
+const testContestStart = 1000000
+
+var testContestInfo = gytypes.ScoreContestInfo{
+	StartTimestamp: time.Unix(testContestStart, 0),
+	PenaltyTime:    20 * 60,
+}
+
+// Judged submission made given minutes after contest start
+func testSubmission(verdict string, score int, minutes int64) gytypes.ScoreSubmissionData {
+	return gytypes.ScoreSubmissionData{
+		Verdict:    verdict,
+		Score:      score,
+		SubmitTime: testContestStart + minutes*60,
+	}
+}
+
+func testAccepted(minutes int64) gytypes.ScoreSubmissionData {
+	return testSubmission(gytypes.SubmissionAccepted, gytypes.ScoreFullPoints, minutes)
+}
+
+func testRejected(score int, minutes int64) gytypes.ScoreSubmissionData {
+	return testSubmission(gytypes.SubmissionWrongAnswer, score, minutes)
+}
+
+func TestAccumulateScore(t *testing.T) {
+	tests := []struct {
+		name         string
+		style        ContestStyle
+		sci          gytypes.ScoreContestInfo
+		history      []gytypes.ScoreSubmissionData
+		score        int
+		count        int
+		acceptedTime int64 // Minutes after contest start, -1 if never accepted
+		penalty      int64
+		solved       bool
+	}{
+		{"icpc accepted first try", icpcContestStyle{}, testContestInfo,
+			[]gytypes.ScoreSubmissionData{testAccepted(30)},
+			1, 1, 30, 0, true},
+		{"icpc wrong attempts penalized", icpcContestStyle{}, testContestInfo,
+			[]gytypes.ScoreSubmissionData{testRejected(0, 10), testRejected(0, 20), testAccepted(30)},
+			1, 3, 30, 40 * 60, true},
+		{"icpc ignores after accepted", icpcContestStyle{}, testContestInfo,
+			[]gytypes.ScoreSubmissionData{testAccepted(5), testRejected(0, 6), testAccepted(7)},
+			1, 1, 5, 0, true},
+		{"icpc never accepted", icpcContestStyle{}, testContestInfo,
+			[]gytypes.ScoreSubmissionData{testRejected(50, 10), testRejected(0, 20)},
+			0, 2, -1, 40 * 60, false},
+		{"ioi best score kept", ioiContestStyle{}, testContestInfo,
+			[]gytypes.ScoreSubmissionData{testRejected(30, 10), testRejected(70, 20), testRejected(50, 30)},
+			70, 3, 20, 0, false},
+		{"ioi full points", ioiContestStyle{}, testContestInfo,
+			[]gytypes.ScoreSubmissionData{testRejected(40, 10), testAccepted(15), testAccepted(40)},
+			100, 3, 15, 0, true},
+		{"atcoder penalty only before improvement", atcoderContestStyle{}, testContestInfo,
+			[]gytypes.ScoreSubmissionData{testRejected(0, 5), testRejected(50, 10), testRejected(0, 15),
+				testAccepted(20), testRejected(0, 25)},
+			100, 5, 20, 40 * 60, true},
+		{"atcoder partial score", atcoderContestStyle{}, testContestInfo,
+			[]gytypes.ScoreSubmissionData{testRejected(60, 10), testRejected(0, 15)},
+			60, 2, 10, 0, false},
+		{"codeforces no decay at start", codeforcesContestStyle{}, testContestInfo,
+			[]gytypes.ScoreSubmissionData{testAccepted(0)},
+			100, 1, 0, 0, true},
+		{"codeforces decayed by time", codeforcesContestStyle{}, testContestInfo,
+			[]gytypes.ScoreSubmissionData{testAccepted(50)},
+			80, 1, 50, 0, true},
+		{"codeforces decayed by wrong attempt", codeforcesContestStyle{}, testContestInfo,
+			[]gytypes.ScoreSubmissionData{testRejected(0, 10), testAccepted(50)},
+			70, 2, 50, 0, true},
+		{"codeforces floored", codeforcesContestStyle{}, testContestInfo,
+			[]gytypes.ScoreSubmissionData{testAccepted(240)},
+			30, 1, 240, 0, true},
+		{"codeforces best of resubmission", codeforcesContestStyle{}, testContestInfo,
+			[]gytypes.ScoreSubmissionData{testAccepted(50), testAccepted(100)},
+			80, 2, 50, 0, true},
+		{"codeforces partial score", codeforcesContestStyle{}, testContestInfo,
+			[]gytypes.ScoreSubmissionData{testRejected(50, 25), testAccepted(50)},
+			70, 2, 50, 0, true},
+		{"codeforces without start time", codeforcesContestStyle{}, gytypes.ScoreContestInfo{},
+			[]gytypes.ScoreSubmissionData{testAccepted(100)},
+			100, 1, 100, 0, true},
+	}
+	for _, tt := range tests {
+		var score gytypes.ScoreProblemData
+		tt.style.AccumulateScore(tt.sci, &score, tt.history)
+		acceptedTime := int64(-1)
+		if score.AcceptedTime > 0 {
+			acceptedTime = (score.AcceptedTime - testContestStart) / 60
+		}
+		if score.Score != tt.score {
+			t.Errorf("%s: score = %d, want %d", tt.name, score.Score, tt.score)
+		}
+		if score.SubmissionCount != tt.count {
+			t.Errorf("%s: submission count = %d, want %d", tt.name, score.SubmissionCount, tt.count)
+		}
+		if acceptedTime != tt.acceptedTime {
+			t.Errorf("%s: accepted time = %d, want %d", tt.name, acceptedTime, tt.acceptedTime)
+		}
+		if score.PenaltyTime != tt.penalty {
+			t.Errorf("%s: penalty = %d, want %d", tt.name, score.PenaltyTime, tt.penalty)
+		}
+		if solved := tt.style.IsSolved(score); solved != tt.solved {
+			t.Errorf("%s: solved = %v, want %v", tt.name, solved, tt.solved)
+		}
+	}
+}
+
+func TestContestantPenalty(t *testing.T) {
+	user := gytypes.ScoreContestantData{
+		LastScoreTime: 50 * 60,
+		Problems: []gytypes.ScoreProblemData{
+			{AcceptedTime: 30 * 60, PenaltyTime: 40 * 60},
+			{AcceptedTime: 50 * 60},
+			{PenaltyTime: 20 * 60},
+		},
+	}
+	tests := []struct {
+		name  string
+		style ContestStyle
+		want  int64
+	}{
+		// Rejected attempts of unsolved problem counted too, as scoreboard always did
+		{"icpc", icpcContestStyle{}, (30 + 40 + 50 + 20) * 60},
+		{"atcoder", atcoderContestStyle{}, (50 + 40 + 20) * 60},
+		{"ioi", ioiContestStyle{}, 0},
+		{"codeforces", codeforcesContestStyle{}, 0},
+	}
+	for _, tt := range tests {
+		if got := tt.style.ContestantPenalty(testContestInfo, user); got != tt.want {
+			t.Errorf("%s: penalty = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestRankContestants(t *testing.T) {
+	tests := []struct {
+		name        string
+		style       ContestStyle
+		contestants gytypes.ScoreContestantDataList
+		wantOrder   []int
+		wantRanks   []int
+	}{
+		{"icpc by solved then penalty", icpcContestStyle{},
+			gytypes.ScoreContestantDataList{
+				{UserId: 1, TotalScore: 2, TotalPenaltyTime: 300},
+				{UserId: 2, TotalScore: 3, TotalPenaltyTime: 900},
+				{UserId: 3, TotalScore: 2, TotalPenaltyTime: 100},
+			},
+			[]int{2, 3, 1}, []int{1, 2, 3}},
+		{"icpc tie never shares rank", icpcContestStyle{},
+			gytypes.ScoreContestantDataList{
+				{UserId: 5, TotalScore: 1, TotalPenaltyTime: 100},
+				{UserId: 4, TotalScore: 1, TotalPenaltyTime: 100},
+			},
+			[]int{4, 5}, []int{1, 2}},
+		{"ioi tie shares rank", ioiContestStyle{},
+			gytypes.ScoreContestantDataList{
+				{UserId: 1, TotalScore: 150},
+				{UserId: 2, TotalScore: 200},
+				{UserId: 3, TotalScore: 150},
+				{UserId: 4, TotalScore: 100},
+			},
+			[]int{2, 1, 3, 4}, []int{1, 2, 2, 4}},
+		{"ioi tie-breakers", ioiContestStyle{tieBreakers: []string{gytypes.ScoreTieBreakSolved, gytypes.ScoreTieBreakTime}},
+			gytypes.ScoreContestantDataList{
+				{UserId: 1, TotalScore: 150, SolvedCount: 1, LastScoreTime: 100},
+				{UserId: 2, TotalScore: 150, SolvedCount: 1, LastScoreTime: 50},
+				{UserId: 3, TotalScore: 150, SolvedCount: 2, LastScoreTime: 900},
+			},
+			[]int{3, 2, 1}, []int{1, 2, 3}},
+		{"atcoder score then penalty", atcoderContestStyle{},
+			gytypes.ScoreContestantDataList{
+				{UserId: 1, TotalScore: 300, TotalPenaltyTime: 600},
+				{UserId: 2, TotalScore: 300, TotalPenaltyTime: 600},
+				{UserId: 3, TotalScore: 300, TotalPenaltyTime: 300},
+			},
+			[]int{3, 1, 2}, []int{1, 2, 2}},
+		{"codeforces score only", codeforcesContestStyle{},
+			gytypes.ScoreContestantDataList{
+				{UserId: 1, TotalScore: 70, TotalPenaltyTime: 0},
+				{UserId: 2, TotalScore: 80, TotalPenaltyTime: 900},
+				{UserId: 3, TotalScore: 70, TotalPenaltyTime: 100},
+			},
+			[]int{2, 1, 3}, []int{1, 2, 2}},
+	}
+	for _, tt := range tests {
+		rankContestants(tt.contestants, tt.style)
+		for i, cs := range tt.contestants {
+			if (cs.UserId != tt.wantOrder[i]) || (cs.RankNumber != tt.wantRanks[i]) {
+				t.Errorf("%s: position %d is user %d rank %d, want user %d rank %d", tt.name, i,
+					cs.UserId, cs.RankNumber, tt.wantOrder[i], tt.wantRanks[i])
+			}
+		}
+	}
+}
@@ -12,6 +12,7 @@ import (
 	"errors"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/thiekus/gargoyle-judge/internal/gylib"
@@ -295,3 +296,356 @@ func dashboardUserDeleteGetEndpoint(w http.ResponseWriter, r *http.Request) {
 	appUsers.AddFlashMessage(w, r, "Success deleting account!", FlashSuccess)
 	http.Redirect(w, r, GetAppUrl(r)+"/dashboard/manageUsers", 302)
 }
+
+type DashboardSlaveItem struct {
+	gytypes.SlaveData
+	RecentJudged int
+}
+
+type DashboardManageSlavesData struct {
+	SlaveCount  int
+	ActiveCount int
+	Slaves      []DashboardSlaveItem
+}
+
+type DashboardSlaveEditData struct {
+	Slave gytypes.SlaveData
+}
+
+// Get slave data from add/edit form
+func parseSlaveForm(r *http.Request) (gytypes.SlaveData, error) {
+	r.ParseForm()
+	weight, _ := strconv.Atoi(r.PostFormValue("weight"))
+	capacity, _ := strconv.Atoi(r.PostFormValue("capacity"))
+	slave := gytypes.SlaveData{
+		Name:     r.PostFormValue("name"),
+		Address:  r.PostFormValue("address"),
+		Enable:   r.PostFormValue("enable") != "",
+		Weight:   weight,
+		Capacity: capacity,
+	}
+	if (slave.Name == "") || (slave.Address == "") {
+		return slave, errors.New("slave name and address must be filled")
+	}
+	if slave.Address == embeddedSlaveAddress {
+		return slave, errors.New("address is reserved for embedded slave")
+	}
+	if slave.Weight <= 0 {
+		return slave, errors.New("slave weight must be positive")
+	}
+	if slave.Capacity < 0 {
+		return slave, errors.New("slave capacity cannot be negative")
+	}
+	return slave, nil
+}
+
+func dashboardManageSlavesGetEndpoint(w http.ResponseWriter, r *http.Request) {
+	ui := appUsers.GetLoggedUserInfo(r)
+	if !ui.Roles.SysAdmin {
+		http.Error(w, "403 Forbidden", http.StatusForbidden)
+		return
+	}
+	log := gylib.GetStdLog()
+	var err error = nil
+	defer func() {
+		if err != nil {
+			log.Error(err)
+			appUsers.AddFlashMessage(w, r, "Error: "+err.Error(), FlashError)
+			http.Redirect(w, r, GetAppUrl(r)+"/dashboard", 302)
+		}
+	}()
+	db, err := OpenDatabase()
+	if err != nil {
+		return
+	}
+	defer db.Close()
+	sdm := NewSubmissionDbModel(db)
+	// Judged count within last 24 hours
+	since := time.Now().Add(-24 * time.Hour).Unix()
+	counts, err := sdm.GetJudgedCountBySlave(since)
+	if err != nil {
+		return
+	}
+	sl := appSlaves.GetSlaves()
+	msd := DashboardManageSlavesData{
+		SlaveCount: len(sl),
+	}
+	for _, slave := range sl {
+		if slave.Active {
+			msd.ActiveCount++
+		}
+		item := DashboardSlaveItem{
+			SlaveData:    slave,
+			RecentJudged: counts[slave.Address],
+		}
+		msd.Slaves = append(msd.Slaves, item)
+	}
+	CompileDashboardPage(w, r, "dashboard_base.html", "dashboard_manageslaves.html",
+		"manageslaves", msd, "")
+}
+
+func dashboardSlaveRefreshGetEndpoint(w http.ResponseWriter, r *http.Request) {
+	ui := appUsers.GetLoggedUserInfo(r)
+	if !ui.Roles.SysAdmin {
+		http.Error(w, "403 Forbidden", http.StatusForbidden)
+		return
+	}
+	log := gylib.GetStdLog()
+	if err := appSlaves.RefreshSlaves(); err != nil {
+		log.Error(err)
+		appUsers.AddFlashMessage(w, r, "Error: "+err.Error(), FlashError)
+	} else {
+		appUsers.AddFlashMessage(w, r, "Slave list refreshed!", FlashSuccess)
+	}
+	http.Redirect(w, r, GetAppUrl(r)+"/dashboard/manageSlaves", 302)
+}
+
+func dashboardSlaveAddGetEndpoint(w http.ResponseWriter, r *http.Request) {
+	ui := appUsers.GetLoggedUserInfo(r)
+	if !ui.Roles.SysAdmin {
+		http.Error(w, "403 Forbidden", http.StatusForbidden)
+		return
+	}
+	sed := DashboardSlaveEditData{
+		Slave: gytypes.SlaveData{
+			Enable:   true,
+			Weight:   1,
+			Capacity: 2,
+		},
+	}
+	CompileDashboardPage(w, r, "dashboard_base.html", "dashboard_slaveedit.html",
+		"manageslaves", sed, "")
+}
+
+func dashboardSlaveAddPostEndpoint(w http.ResponseWriter, r *http.Request) {
+	ui := appUsers.GetLoggedUserInfo(r)
+	if !ui.Roles.SysAdmin {
+		http.Error(w, "403 Forbidden", http.StatusForbidden)
+		return
+	}
+	log := gylib.GetStdLog()
+	var err error = nil
+	defer func() {
+		if err != nil {
+			log.Error(err)
+			appUsers.AddFlashMessage(w, r, "Error: "+err.Error(), FlashError)
+			http.Redirect(w, r, GetAppUrl(r)+"/dashboard/slaveAdd", 302)
+		}
+	}()
+	slave, err := parseSlaveForm(r)
+	if err != nil {
+		return
+	}
+	// Added by admin, no need to approve
+	slave.Approved = true
+	db, err := OpenDatabase()
+	if err != nil {
+		return
+	}
+	defer db.Close()
+	sdm := NewSlaveDbModel(db)
+	if err = sdm.InsertSlave(slave); err != nil {
+		return
+	}
+	if err = appSlaves.RefreshSlaves(); err != nil {
+		return
+	}
+	appUsers.AddFlashMessage(w, r, "Success adding new slave!", FlashSuccess)
+	http.Redirect(w, r, GetAppUrl(r)+"/dashboard/manageSlaves", 302)
+}
+
+func dashboardSlaveEditGetEndpoint(w http.ResponseWriter, r *http.Request) {
+	ui := appUsers.GetLoggedUserInfo(r)
+	if !ui.Roles.SysAdmin {
+		http.Error(w, "403 Forbidden", http.StatusForbidden)
+		return
+	}
+	log := gylib.GetStdLog()
+	var err error = nil
+	defer func() {
+		if err != nil {
+			log.Error(err)
+			appUsers.AddFlashMessage(w, r, "Error: "+err.Error(), FlashError)
+			http.Redirect(w, r, GetAppUrl(r)+"/dashboard/manageSlaves", 302)
+		}
+	}()
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		return
+	}
+	db, err := OpenDatabase()
+	if err != nil {
+		return
+	}
+	defer db.Close()
+	sdm := NewSlaveDbModel(db)
+	slave, err := sdm.GetSlaveById(id)
+	if err != nil {
+		return
+	}
+	sed := DashboardSlaveEditData{
+		Slave: slave,
+	}
+	CompileDashboardPage(w, r, "dashboard_base.html", "dashboard_slaveedit.html",
+		"manageslaves", sed, "")
+}
+
+func dashboardSlaveEditPostEndpoint(w http.ResponseWriter, r *http.Request) {
+	ui := appUsers.GetLoggedUserInfo(r)
+	if !ui.Roles.SysAdmin {
+		http.Error(w, "403 Forbidden", http.StatusForbidden)
+		return
+	}
+	log := gylib.GetStdLog()
+	var err error = nil
+	defer func() {
+		if err != nil {
+			log.Error(err)
+			appUsers.AddFlashMessage(w, r, "Error: "+err.Error(), FlashError)
+			http.Redirect(w, r, GetAppUrl(r)+"/dashboard/manageSlaves", 302)
+		}
+	}()
+	slave, err := parseSlaveForm(r)
+	if err != nil {
+		return
+	}
+	id, err := strconv.Atoi(r.PostFormValue("id"))
+	if err != nil {
+		err = errors.New("invalid slave id")
+		return
+	}
+	db, err := OpenDatabase()
+	if err != nil {
+		return
+	}
+	defer db.Close()
+	sdm := NewSlaveDbModel(db)
+	if err = sdm.ModifySlave(id, slave); err != nil {
+		return
+	}
+	if err = appSlaves.RefreshSlaves(); err != nil {
+		return
+	}
+	appUsers.AddFlashMessage(w, r, "Success updating slave!", FlashSuccess)
+	http.Redirect(w, r, GetAppUrl(r)+"/dashboard/manageSlaves", 302)
+}
+
+// Enable or disable slave, toggling current state
+func dashboardSlaveToggleGetEndpoint(w http.ResponseWriter, r *http.Request) {
+	ui := appUsers.GetLoggedUserInfo(r)
+	if !ui.Roles.SysAdmin {
+		http.Error(w, "403 Forbidden", http.StatusForbidden)
+		return
+	}
+	log := gylib.GetStdLog()
+	var err error = nil
+	defer func() {
+		if err != nil {
+			log.Error(err)
+			appUsers.AddFlashMessage(w, r, "Error: "+err.Error(), FlashError)
+			http.Redirect(w, r, GetAppUrl(r)+"/dashboard/manageSlaves", 302)
+		}
+	}()
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		return
+	}
+	db, err := OpenDatabase()
+	if err != nil {
+		return
+	}
+	defer db.Close()
+	sdm := NewSlaveDbModel(db)
+	slave, err := sdm.GetSlaveById(id)
+	if err != nil {
+		return
+	}
+	slave.Enable = !slave.Enable
+	if err = sdm.ModifySlave(id, slave); err != nil {
+		return
+	}
+	if err = appSlaves.RefreshSlaves(); err != nil {
+		return
+	}
+	if slave.Enable {
+		appUsers.AddFlashMessage(w, r, "Slave "+slave.Name+" enabled!", FlashSuccess)
+	} else {
+		appUsers.AddFlashMessage(w, r, "Slave "+slave.Name+" disabled!", FlashSuccess)
+	}
+	http.Redirect(w, r, GetAppUrl(r)+"/dashboard/manageSlaves", 302)
+}
+
+func dashboardSlaveDeleteGetEndpoint(w http.ResponseWriter, r *http.Request) {
+	ui := appUsers.GetLoggedUserInfo(r)
+	if !ui.Roles.SysAdmin {
+		http.Error(w, "403 Forbidden", http.StatusForbidden)
+		return
+	}
+	log := gylib.GetStdLog()
+	var err error = nil
+	defer func() {
+		if err != nil {
+			log.Error(err)
+			appUsers.AddFlashMessage(w, r, "Error: "+err.Error(), FlashError)
+			http.Redirect(w, r, GetAppUrl(r)+"/dashboard/manageSlaves", 302)
+		}
+	}()
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		return
+	}
+	db, err := OpenDatabase()
+	if err != nil {
+		return
+	}
+	defer db.Close()
+	sdm := NewSlaveDbModel(db)
+	if err = sdm.DeleteSlaveById(id); err != nil {
+		return
+	}
+	if err = appSlaves.RefreshSlaves(); err != nil {
+		return
+	}
+	appUsers.AddFlashMessage(w, r, "Success removing slave!", FlashSuccess)
+	http.Redirect(w, r, GetAppUrl(r)+"/dashboard/manageSlaves", 302)
+}
+
+// Approve self-registered slave so it can receive judging jobs
+func dashboardSlaveApproveGetEndpoint(w http.ResponseWriter, r *http.Request) {
+	ui := appUsers.GetLoggedUserInfo(r)
+	if !ui.Roles.SysAdmin {
+		http.Error(w, "403 Forbidden", http.StatusForbidden)
+		return
+	}
+	log := gylib.GetStdLog()
+	var err error = nil
+	defer func() {
+		if err != nil {
+			log.Error(err)
+			appUsers.AddFlashMessage(w, r, "Error: "+err.Error(), FlashError)
+			http.Redirect(w, r, GetAppUrl(r)+"/dashboard/manageSlaves", 302)
+		}
+	}()
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		return
+	}
+	db, err := OpenDatabase()
+	if err != nil {
+		return
+	}
+	defer db.Close()
+	sdm := NewSlaveDbModel(db)
+	if err = sdm.SetSlaveApproved(id, true); err != nil {
+		return
+	}
+	if err = appSlaves.RefreshSlaves(); err != nil {
+		return
+	}
+	appUsers.AddFlashMessage(w, r, "Slave approved!", FlashSuccess)
+	http.Redirect(w, r, GetAppUrl(r)+"/dashboard/manageSlaves", 302)
+}
@@ -11,9 +11,11 @@ package main
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io/ioutil"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/thiekus/gargoyle-judge/internal/gylib"
@@ -141,6 +143,68 @@ func dashboardViewScoreboardGetEndpoint(w http.ResponseWriter, r *http.Request)
 		"scoreboard", dvsd, "")
 }
 
+func dashboardScoreboardExportGetEndpoint(w http.ResponseWriter, r *http.Request) {
+	ui := appUsers.GetLoggedUserInfo(r)
+	vars := mux.Vars(r)
+	id, _ := strconv.Atoi(vars["id"])
+	// Private scoreboard exported only for jury, as shown on scoreboard page
+	sb, err := appScoreboard.GetScoreboardByUser(ui, id)
+	if err != nil {
+		http.Error(w, "500 Internal Server Error: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	public := !ui.IsAdmin() && !ui.IsJury()
+	data, contentType, filename, err := ExportScoreboard(sb, public, vars["format"])
+	if err != nil {
+		http.Error(w, "400 Bad Request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", filename))
+	w.Write(data)
+}
+
+// Server-Sent Events stream of own submissions and, if contest given, its scoreboard
+func dashboardLiveEventsGetEndpoint(w http.ResponseWriter, r *http.Request) {
+	ui := appUsers.GetLoggedUserInfo(r)
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "500 Internal Server Error: streaming not supported", http.StatusInternalServerError)
+		return
+	}
+	contestId, _ := strconv.Atoi(r.URL.Query().Get("contest"))
+	if contestId > 0 {
+		// Same visibility as scoreboard page, public one may be disallowed
+		if _, err := appScoreboard.GetScoreboardByUser(ui, contestId); err != nil {
+			http.Error(w, "403 Forbidden: "+err.Error(), http.StatusForbidden)
+			return
+		}
+	}
+	client := appLiveUpdates.Subscribe(ui.Id, contestId, ui.IsAdmin() || ui.IsJury())
+	defer appLiveUpdates.Unsubscribe(client)
+	// Content type must be set before writing, so compression handler never buffers it
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	fmt.Fprint(w, ": connected\n\n")
+	flusher.Flush()
+	keepAlive := time.NewTicker(liveKeepAlive)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case event, ok := <-client.Events:
+			if !ok {
+				return
+			}
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Name, event.Data)
+		case <-keepAlive.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+		}
+		flusher.Flush()
+	}
+}
+
 func dashboardNotificationsEndpoint(w http.ResponseWriter, r *http.Request) {
 	log := gylib.GetStdLog()
 	var err error = nil
@@ -180,6 +244,25 @@ func dashboardNotificationsEndpoint(w http.ResponseWriter, r *http.Request) {
 		"notifications", dnd, "")
 }
 
+func dashboardQueueStatusGetEndpoint(w http.ResponseWriter, r *http.Request) {
+	log := gylib.GetStdLog()
+	var err error = nil
+	defer func() {
+		if err != nil {
+			log.Error(err)
+			appUsers.AddFlashMessage(w, r, "Error: "+err.Error(), FlashError)
+			http.Redirect(w, r, GetAppUrl(r)+"/dashboard", 302)
+		}
+	}()
+	ui := appUsers.GetLoggedUserInfo(r)
+	status, err := appJudgeQueue.GetStatus(ui.Id, ui.IsJury() || ui.IsAdmin())
+	if err != nil {
+		return
+	}
+	CompileDashboardPage(w, r, "dashboard_base.html", "dashboard_queuestatus.html",
+		"queueStatus", status, "")
+}
+
 func dashboardProfileGetEndpoint(w http.ResponseWriter, r *http.Request) {
 	cl, _ := GetCountryListName()
 	dpd := DashboardProfileData{
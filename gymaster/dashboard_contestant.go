@@ -223,7 +223,7 @@ func dashboardProblemPostEndpoint(w http.ResponseWriter, r *http.Request) {
 		errRedirect = GetAppUrl(r) + "/dashboard/problem/" + strconv.Itoa(problemId)
 		return
 	}
-	sub, err := NewSubmissionProcessor(&appSlaves, problemId, userId, langId, code)
+	sub, err := NewSubmissionProcessor(&appJudgeQueue, problemId, userId, langId, code)
 	if err != nil {
 		errRedirect = GetAppUrl(r) + "/dashboard/problem/" + strconv.Itoa(problemId)
 		return
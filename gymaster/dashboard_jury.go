@@ -9,7 +9,9 @@ package main
  * file, You can obtain one at https://mozilla.org/MPL/2.0/. */
 
 import (
+	"fmt"
 	"net/http"
+	"strconv"
 
 	"github.com/thiekus/gargoyle-judge/internal/gylib"
 	"github.com/thiekus/gargoyle-judge/internal/gytypes"
@@ -82,3 +84,363 @@ func dashboardContestAddGetEndpoint(w http.ResponseWriter, r *http.Request) {
 	CompileDashboardPage(w, r, "dashboard_base.html", "dashboard_contestadd.html",
 		"managecontests", cad, "")
 }
+
+type DashboardRejudgeData struct {
+	Contests  []gytypes.ContestData
+	Languages gytypes.LanguageProgramMap
+	Verdicts  []string
+}
+
+func dashboardRejudgeGetEndpoint(w http.ResponseWriter, r *http.Request) {
+	ui := appUsers.GetLoggedUserInfo(r)
+	if !ui.Roles.Jury {
+		http.Error(w, "403 Forbidden", http.StatusForbidden)
+		return
+	}
+	log := gylib.GetStdLog()
+	var err error = nil
+	defer func() {
+		if err != nil {
+			log.Error(err)
+			appUsers.AddFlashMessage(w, r, "Error: "+err.Error(), FlashError)
+			http.Redirect(w, r, GetAppUrl(r)+"/dashboard", 302)
+		}
+	}()
+	db, err := OpenDatabase()
+	if err != nil {
+		return
+	}
+	defer db.Close()
+	cdm := NewContestDbModel(db)
+	cl, err := cdm.GetContestList()
+	if err != nil {
+		return
+	}
+	langs, err := appLangPrograms.GetLanguageMap()
+	if err != nil {
+		return
+	}
+	rd := DashboardRejudgeData{
+		Contests:  cl,
+		Languages: langs,
+		Verdicts: []string{
+			gytypes.SubmissionAccepted,
+			gytypes.SubmissionPresentationError,
+			gytypes.SubmissionWrongAnswer,
+			gytypes.SubmissionTimeLimitExceeded,
+			gytypes.SubmissionMemoryLimitExceeded,
+			gytypes.SubmissionOutputLimitExceeded,
+			gytypes.SubmissionRuntimeError,
+			gytypes.SubmissionCompilerError,
+			gytypes.SubmissionRestrictedFunction,
+			gytypes.SubmissionError,
+		},
+	}
+	CompileDashboardPage(w, r, "dashboard_base.html", "dashboard_rejudge.html",
+		"rejudge", rd, "")
+}
+
+func dashboardRejudgePostEndpoint(w http.ResponseWriter, r *http.Request) {
+	ui := appUsers.GetLoggedUserInfo(r)
+	if !ui.Roles.Jury {
+		http.Error(w, "403 Forbidden", http.StatusForbidden)
+		return
+	}
+	log := gylib.GetStdLog()
+	var err error = nil
+	defer func() {
+		if err != nil {
+			log.Error(err)
+			appUsers.AddFlashMessage(w, r, "Error: "+err.Error(), FlashError)
+			http.Redirect(w, r, GetAppUrl(r)+"/dashboard/rejudge", 302)
+		}
+	}()
+	r.ParseForm()
+	filter := gytypes.RejudgeFilter{}
+	filter.SubmissionId, _ = strconv.Atoi(r.PostFormValue("submission_id"))
+	filter.ProblemId, _ = strconv.Atoi(r.PostFormValue("problem_id"))
+	filter.ContestId, _ = strconv.Atoi(r.PostFormValue("contest_id"))
+	filter.LanguageId, _ = strconv.Atoi(r.PostFormValue("lang_id"))
+	filter.Verdict = r.PostFormValue("verdict")
+	count, err := appJudgeQueue.Rejudge(filter)
+	if err != nil {
+		return
+	}
+	log.Printf("uid:%d requested rejudge %+v", ui.Id, filter)
+	appUsers.AddFlashMessage(w, r, fmt.Sprintf("%d submission(s) queued for rejudge!", count), FlashSuccess)
+	// Back into submission if only single submission rejudged
+	if filter.SubmissionId > 0 {
+		http.Redirect(w, r, GetAppUrl(r)+"/dashboard/userViewSubmission/"+strconv.Itoa(filter.SubmissionId), 302)
+	} else {
+		http.Redirect(w, r, GetAppUrl(r)+"/dashboard/rejudge", 302)
+	}
+}
+
+type DashboardRebuildScoreboardData struct {
+	Contests  []gytypes.ContestData
+	ContestId int
+	HasPlan   bool
+	Plan      gytypes.ScoreRebuildPlan
+}
+
+func dashboardRebuildScoreboardGetEndpoint(w http.ResponseWriter, r *http.Request) {
+	ui := appUsers.GetLoggedUserInfo(r)
+	if !ui.Roles.Jury {
+		http.Error(w, "403 Forbidden", http.StatusForbidden)
+		return
+	}
+	log := gylib.GetStdLog()
+	var err error = nil
+	defer func() {
+		if err != nil {
+			log.Error(err)
+			appUsers.AddFlashMessage(w, r, "Error: "+err.Error(), FlashError)
+			http.Redirect(w, r, GetAppUrl(r)+"/dashboard", 302)
+		}
+	}()
+	db, err := OpenDatabase()
+	if err != nil {
+		return
+	}
+	defer db.Close()
+	cdm := NewContestDbModel(db)
+	cl, err := cdm.GetContestList()
+	if err != nil {
+		return
+	}
+	rd := DashboardRebuildScoreboardData{
+		Contests: cl,
+	}
+	// Only preview differences, written after confirmed by POST
+	if contestId, _ := strconv.Atoi(r.URL.Query().Get("contest")); contestId > 0 {
+		plan, err := appScoreboard.RebuildContestScores(contestId, false)
+		if err != nil {
+			log.Error(err)
+			appUsers.AddFlashMessage(w, r, "Error: "+err.Error(), FlashError)
+			http.Redirect(w, r, GetAppUrl(r)+"/dashboard/rebuildScoreboard", 302)
+			return
+		}
+		rd.ContestId = contestId
+		rd.HasPlan = true
+		rd.Plan = plan
+	}
+	CompileDashboardPage(w, r, "dashboard_base.html", "dashboard_rebuildscoreboard.html",
+		"rebuildScoreboard", rd, "")
+}
+
+func dashboardRebuildScoreboardPostEndpoint(w http.ResponseWriter, r *http.Request) {
+	ui := appUsers.GetLoggedUserInfo(r)
+	if !ui.Roles.Jury {
+		http.Error(w, "403 Forbidden", http.StatusForbidden)
+		return
+	}
+	log := gylib.GetStdLog()
+	var err error = nil
+	defer func() {
+		if err != nil {
+			log.Error(err)
+			appUsers.AddFlashMessage(w, r, "Error: "+err.Error(), FlashError)
+			http.Redirect(w, r, GetAppUrl(r)+"/dashboard/rebuildScoreboard", 302)
+		}
+	}()
+	r.ParseForm()
+	contestId, err := strconv.Atoi(r.PostFormValue("contest_id"))
+	if err != nil {
+		return
+	}
+	plan, err := appScoreboard.RebuildContestScores(contestId, true)
+	if err != nil {
+		return
+	}
+	log.Printf("uid:%d rebuilt scoreboard of contest %d with %d change(s)", ui.Id, contestId, len(plan.Diffs))
+	appUsers.AddFlashMessage(w, r, fmt.Sprintf("Scoreboard rebuilt with %d change(s)!", len(plan.Diffs)), FlashSuccess)
+	http.Redirect(w, r, GetAppUrl(r)+"/dashboard/rebuildScoreboard?contest="+strconv.Itoa(contestId), 302)
+}
+
+func dashboardCancelSubmissionPostEndpoint(w http.ResponseWriter, r *http.Request) {
+	ui := appUsers.GetLoggedUserInfo(r)
+	if !ui.Roles.Jury {
+		http.Error(w, "403 Forbidden", http.StatusForbidden)
+		return
+	}
+	log := gylib.GetStdLog()
+	r.ParseForm()
+	subId, _ := strconv.Atoi(r.PostFormValue("submission_id"))
+	redirect := GetAppUrl(r) + "/dashboard/userViewSubmission/" + strconv.Itoa(subId)
+	if err := appJudgeQueue.Cancel(subId); err != nil {
+		log.Error(err)
+		appUsers.AddFlashMessage(w, r, "Error: "+err.Error(), FlashError)
+	} else {
+		log.Printf("uid:%d cancelled submission %d", ui.Id, subId)
+		appUsers.AddFlashMessage(w, r, "Submission cancelled!", FlashSuccess)
+	}
+	http.Redirect(w, r, redirect, 302)
+}
+
+type DashboardJudgeMismatchesData struct {
+	Mismatches []gytypes.JudgeMismatchData
+}
+
+func dashboardJudgeMismatchesGetEndpoint(w http.ResponseWriter, r *http.Request) {
+	ui := appUsers.GetLoggedUserInfo(r)
+	if !ui.Roles.Jury {
+		http.Error(w, "403 Forbidden", http.StatusForbidden)
+		return
+	}
+	log := gylib.GetStdLog()
+	var err error = nil
+	defer func() {
+		if err != nil {
+			log.Error(err)
+			appUsers.AddFlashMessage(w, r, "Error: "+err.Error(), FlashError)
+			http.Redirect(w, r, GetAppUrl(r)+"/dashboard", 302)
+		}
+	}()
+	db, err := OpenDatabase()
+	if err != nil {
+		return
+	}
+	defer db.Close()
+	sdm := NewSubmissionDbModel(db)
+	ml, err := sdm.GetJudgeMismatchList()
+	if err != nil {
+		return
+	}
+	md := DashboardJudgeMismatchesData{
+		Mismatches: ml,
+	}
+	CompileDashboardPage(w, r, "dashboard_base.html", "dashboard_judgemismatches.html",
+		"judgeMismatches", md, "")
+}
+
+func dashboardJudgeMismatchResolvePostEndpoint(w http.ResponseWriter, r *http.Request) {
+	ui := appUsers.GetLoggedUserInfo(r)
+	if !ui.Roles.Jury {
+		http.Error(w, "403 Forbidden", http.StatusForbidden)
+		return
+	}
+	log := gylib.GetStdLog()
+	r.ParseForm()
+	mismatchId, _ := strconv.Atoi(r.PostFormValue("mismatch_id"))
+	resolution := r.PostFormValue("resolution")
+	if err := appJudgeQueue.ResolveMismatch(mismatchId, resolution, ui.Id); err != nil {
+		log.Error(err)
+		appUsers.AddFlashMessage(w, r, "Error: "+err.Error(), FlashError)
+	} else {
+		log.Printf("uid:%d resolved judge mismatch %d as %s", ui.Id, mismatchId, resolution)
+		appUsers.AddFlashMessage(w, r, "Judge mismatch resolved!", FlashSuccess)
+	}
+	http.Redirect(w, r, GetAppUrl(r)+"/dashboard/judgeMismatches", 302)
+}
+
+type DashboardResolverData struct {
+	Contests  []gytypes.ContestData
+	ContestId int
+	HasStatus bool
+	Status    ResolverStatusData
+}
+
+func dashboardResolverGetEndpoint(w http.ResponseWriter, r *http.Request) {
+	ui := appUsers.GetLoggedUserInfo(r)
+	if !ui.Roles.Jury {
+		http.Error(w, "403 Forbidden", http.StatusForbidden)
+		return
+	}
+	log := gylib.GetStdLog()
+	var err error = nil
+	defer func() {
+		if err != nil {
+			log.Error(err)
+			appUsers.AddFlashMessage(w, r, "Error: "+err.Error(), FlashError)
+			http.Redirect(w, r, GetAppUrl(r)+"/dashboard", 302)
+		}
+	}()
+	db, err := OpenDatabase()
+	if err != nil {
+		return
+	}
+	defer db.Close()
+	cdm := NewContestDbModel(db)
+	cl, err := cdm.GetContestList()
+	if err != nil {
+		return
+	}
+	rd := DashboardResolverData{
+		Contests: cl,
+	}
+	if contestId, _ := strconv.Atoi(r.URL.Query().Get("contest")); contestId > 0 {
+		status, err := appScoreboard.GetResolverStatus(contestId)
+		if err != nil {
+			log.Error(err)
+			appUsers.AddFlashMessage(w, r, "Error: "+err.Error(), FlashError)
+			http.Redirect(w, r, GetAppUrl(r)+"/dashboard/resolver", 302)
+			return
+		}
+		rd.ContestId = contestId
+		rd.HasStatus = true
+		rd.Status = status
+	}
+	CompileDashboardPage(w, r, "dashboard_base.html", "dashboard_resolver.html",
+		"resolver", rd, "")
+}
+
+func dashboardResolverRevealPostEndpoint(w http.ResponseWriter, r *http.Request) {
+	ui := appUsers.GetLoggedUserInfo(r)
+	if !ui.Roles.Jury {
+		http.Error(w, "403 Forbidden", http.StatusForbidden)
+		return
+	}
+	log := gylib.GetStdLog()
+	r.ParseForm()
+	contestId, _ := strconv.Atoi(r.PostFormValue("contest_id"))
+	redirect := GetAppUrl(r) + "/dashboard/resolver?contest=" + strconv.Itoa(contestId)
+	if step, err := appScoreboard.RevealNextScore(contestId, ui.Id); err != nil {
+		log.Error(err)
+		appUsers.AddFlashMessage(w, r, "Error: "+err.Error(), FlashError)
+	} else {
+		result := "not solved"
+		if step.Solved {
+			result = "solved"
+		}
+		log.Printf("uid:%d revealed problem %s of %s on contest %d", ui.Id, step.ProblemName, step.UserName, contestId)
+		appUsers.AddFlashMessage(w, r, fmt.Sprintf("%s %s problem %s, rank %d to %d (%d left)", step.UserName, result,
+			step.ProblemName, step.RankBefore, step.RankAfter, step.Remaining), FlashSuccess)
+	}
+	http.Redirect(w, r, redirect, 302)
+}
+
+func dashboardResolverExportGetEndpoint(w http.ResponseWriter, r *http.Request) {
+	ui := appUsers.GetLoggedUserInfo(r)
+	if !ui.Roles.Jury {
+		http.Error(w, "403 Forbidden", http.StatusForbidden)
+		return
+	}
+	contestId, _ := strconv.Atoi(r.URL.Query().Get("contest"))
+	data, err := appScoreboard.GetResolverExport(contestId)
+	if err != nil {
+		http.Error(w, "500 Internal Server Error: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"event-feed-%d.json\"", contestId))
+	w.Write(data)
+}
+
+func dashboardResolverUnfreezePostEndpoint(w http.ResponseWriter, r *http.Request) {
+	ui := appUsers.GetLoggedUserInfo(r)
+	if !ui.Roles.Jury {
+		http.Error(w, "403 Forbidden", http.StatusForbidden)
+		return
+	}
+	log := gylib.GetStdLog()
+	r.ParseForm()
+	contestId, _ := strconv.Atoi(r.PostFormValue("contest_id"))
+	if err := appScoreboard.UnfreezeContest(contestId, ui.Id); err != nil {
+		log.Error(err)
+		appUsers.AddFlashMessage(w, r, "Error: "+err.Error(), FlashError)
+		http.Redirect(w, r, GetAppUrl(r)+"/dashboard/resolver?contest="+strconv.Itoa(contestId), 302)
+		return
+	}
+	appUsers.AddFlashMessage(w, r, "Public scoreboard unfrozen!", FlashSuccess)
+	http.Redirect(w, r, GetAppUrl(r)+"/dashboard/resolver", 302)
+}
@@ -15,9 +15,12 @@ import (
 	"fmt"
 	"io/ioutil"
 	"net/url"
+	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 	"text/template"
+	"time"
 
 	_ "github.com/denisenkom/go-mssqldb"
 	_ "github.com/go-sql-driver/mysql"
@@ -29,6 +32,17 @@ type DbContext struct {
 	driver string
 }
 
+// Transaction with same query preprocessing as its DbContext
+type DbTx struct {
+	tx  *sql.Tx
+	ctx *DbContext
+}
+
+// Either database or its transaction, for queries run on both
+type DbPreparer interface {
+	Prepare(query string) (*sql.Stmt, error)
+}
+
 type DbParseVariables struct {
 	Driver        string
 	DatabaseName  string
@@ -142,7 +156,7 @@ func (d *DbContext) Ping() error {
 	return d.db.Ping()
 }
 
-func (d *DbContext) Prepare(query string) (*sql.Stmt, error) {
+func (d *DbContext) parsePrepareQuery(query string) (string, error) {
 	if d.driver == "sqlserver" {
 		// Dirty job for Ms SQL Server: prepare statement param are differ than MySQL and sqlite
 		c := 1
@@ -151,13 +165,47 @@ func (d *DbContext) Prepare(query string) (*sql.Stmt, error) {
 			c++
 		}
 	}
-	if q, err := d.ParsePreprocessor(query); err != nil {
+	return d.ParsePreprocessor(query)
+}
+
+func (d *DbContext) Prepare(query string) (*sql.Stmt, error) {
+	if q, err := d.parsePrepareQuery(query); err != nil {
 		return nil, err
 	} else {
 		return d.db.Prepare(q)
 	}
 }
 
+func (d *DbContext) Begin() (DbTx, error) {
+	tx, err := d.db.Begin()
+	return DbTx{tx: tx, ctx: d}, err
+}
+
+func (t *DbTx) Prepare(query string) (*sql.Stmt, error) {
+	if q, err := t.ctx.parsePrepareQuery(query); err != nil {
+		return nil, err
+	} else {
+		return t.tx.Prepare(q)
+	}
+}
+
+func (t *DbTx) Exec(query string, args ...interface{}) (sql.Result, error) {
+	if q, err := t.ctx.ParsePreprocessor(query); err != nil {
+		return nil, err
+	} else {
+		return t.tx.Exec(q, args...)
+	}
+}
+
+func (t *DbTx) Commit() error {
+	return t.tx.Commit()
+}
+
+// Safe to defer after commit, it's no-op then
+func (t *DbTx) Rollback() error {
+	return t.tx.Rollback()
+}
+
 func (d *DbContext) Query(query string, args ...interface{}) (*sql.Rows, error) {
 	if q, err := d.ParsePreprocessor(query); err != nil {
 		return nil, err
@@ -180,6 +228,127 @@ func CreateBlankDatabase() error {
 		log.Error(err)
 		return err
 	}
+	if err = markDbMigrationsApplied(db); err != nil {
+		log.Error(err)
+		return err
+	}
 	log.Print("Create new database table done successfully!")
 	return nil
 }
+
+func getDbMigrationList() ([]string, error) {
+	files, err := filepath.Glob(gylib.ConcatByProgramLibDir("./migrations/*.sql"))
+	if err != nil {
+		return nil, err
+	}
+	var migrations []string
+	for _, f := range files {
+		migrations = append(migrations, filepath.Base(f))
+	}
+	// Migration must be applied by its numbered file name order
+	sort.Strings(migrations)
+	return migrations, nil
+}
+
+func prepareDbMigrationTable(db DbContext) error {
+	query := `-- {{if eq .Driver "sqlserver"}}
+        IF OBJECT_ID('{{.TablePrefix}}migrations', 'U') IS NULL CREATE TABLE {{.TablePrefix}}migrations (
+        -- {{else}}
+        CREATE TABLE IF NOT EXISTS {{.TablePrefix}}migrations (
+        -- {{end}}
+            name VARCHAR(100) PRIMARY KEY NOT NULL,
+            applied_time INTEGER NOT NULL DEFAULT 0
+        )`
+	_, err := db.Exec(query)
+	return err
+}
+
+func insertDbMigrationRecord(db DbPreparer, name string) error {
+	query := `INSERT INTO {{.TablePrefix}}migrations (name, applied_time) VALUES (?, ?)`
+	stmt, err := db.Prepare(query)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	_, err = stmt.Exec(name, time.Now().Unix())
+	return err
+}
+
+// Mark all migrations as applied, used after blank database creation since default.sql is latest schema
+func markDbMigrationsApplied(db DbContext) error {
+	if err := prepareDbMigrationTable(db); err != nil {
+		return err
+	}
+	migrations, err := getDbMigrationList()
+	if err != nil {
+		return err
+	}
+	for _, name := range migrations {
+		if err = insertDbMigrationRecord(&db, name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Apply migration and record it at once. MySQL commits implicitly on schema changes,
+// so migrations there must stay safe to run again
+func applyDbMigration(db DbContext, name string, migrationSql string) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err = tx.Exec(migrationSql); err != nil {
+		return fmt.Errorf("migration %s failed: %s", name, err.Error())
+	}
+	if err = insertDbMigrationRecord(&tx, name); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Apply schema changes from lib/migrations into existing database that not yet applied
+func MigrateDatabase() error {
+	log := gylib.GetStdLog()
+	db, err := OpenDatabaseEx(appConfig.DbDriver, true)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	if err = prepareDbMigrationTable(db); err != nil {
+		return err
+	}
+	rows, err := db.Query(`SELECT name FROM {{.TablePrefix}}migrations`)
+	if err != nil {
+		return err
+	}
+	applied := make(map[string]bool)
+	for rows.Next() {
+		var name string
+		if err = rows.Scan(&name); err != nil {
+			rows.Close()
+			return err
+		}
+		applied[name] = true
+	}
+	rows.Close()
+	migrations, err := getDbMigrationList()
+	if err != nil {
+		return err
+	}
+	for _, name := range migrations {
+		if applied[name] {
+			continue
+		}
+		log.Printf("Applying database migration %s...", name)
+		migrationSql, err := ioutil.ReadFile(gylib.ConcatByProgramLibDir("./migrations/" + name))
+		if err != nil {
+			return err
+		}
+		if err = applyDbMigration(db, name, string(migrationSql)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
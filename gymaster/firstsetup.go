@@ -50,6 +50,8 @@ func firstSetupGetEndpoint(w http.ResponseWriter, r *http.Request) {
 			cfg.HasFirstSetup = true
 			saveConfigData(cfg)
 			appConfig = getConfigData()
+			// Judging wasn't started without database
+			startJudging()
 		}
 	} else {
 		http.Error(w, "403 Forbidden", 403)
@@ -0,0 +1,803 @@
+package main
+
+/* GargoyleJudge - Simple Judgement System for Competitive Programming
+ * Copyright (C) Thiekus 2019
+ * Visit www.khayalan.id for updates
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/. */
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/thiekus/gargoyle-judge/internal/gylib"
+	"github.com/thiekus/gargoyle-judge/internal/gyrpc"
+	"github.com/thiekus/gargoyle-judge/internal/gytypes"
+)
+
+// Interval of polling queue when nothing notified
+const judgeQueuePollInterval = 5 * time.Second
+
+// Upper bound of delay between judging retries
+const judgeMaxBackoff = 30 * time.Second
+
+// Upper bound of runs of borderline test case, whatever configured
+const maxTimingRerun = 10
+
+// Remember when each user (or group) last served, for round-robin dispatching
+type judgeFairness struct {
+	mutex      sync.Mutex
+	serial     int64
+	lastServed map[string]int64
+	byGroup    bool
+}
+
+func (jf *judgeFairness) getKey(userId int) string {
+	if jf.byGroup {
+		// Team members share their turn
+		if ui := appUsers.GetUserById(userId); (ui != nil) && (len(ui.Groups) > 0) {
+			return "g" + strconv.Itoa(ui.Groups[0].GroupId)
+		}
+	}
+	return "u" + strconv.Itoa(userId)
+}
+
+// Reorder queue within same priority, least recently served user first then by submission order
+func (jf *judgeFairness) order(queue []gytypes.JudgeQueueData) {
+	keys := make([]string, len(queue))
+	for i, item := range queue {
+		keys[i] = jf.getKey(item.UserId)
+	}
+	jf.mutex.Lock()
+	turns := make([]int64, len(queue))
+	for i, key := range keys {
+		turns[i] = jf.lastServed[key]
+	}
+	jf.mutex.Unlock()
+	// Sort index instead, so keys and turns stay aligned with items
+	index := make([]int, len(queue))
+	for i := range index {
+		index[i] = i
+	}
+	sort.SliceStable(index, func(a, b int) bool {
+		ia, ib := index[a], index[b]
+		if queue[ia].Priority != queue[ib].Priority {
+			return queue[ia].Priority > queue[ib].Priority
+		}
+		return turns[ia] < turns[ib]
+	})
+	sorted := make([]gytypes.JudgeQueueData, len(queue))
+	for i, idx := range index {
+		sorted[i] = queue[idx]
+	}
+	copy(queue, sorted)
+}
+
+func (jf *judgeFairness) served(userId int) {
+	key := jf.getKey(userId)
+	jf.mutex.Lock()
+	defer jf.mutex.Unlock()
+	jf.serial++
+	jf.lastServed[key] = jf.serial
+}
+
+// Submission being judged by this master, so it can be cancelled
+type judgeRunningItem struct {
+	slave     gytypes.SlaveData
+	cancelled bool
+}
+
+type judgeRunningMap struct {
+	mutex sync.Mutex
+	items map[int]*judgeRunningItem
+}
+
+func (jr *judgeRunningMap) add(id int, slave gytypes.SlaveData) {
+	jr.mutex.Lock()
+	defer jr.mutex.Unlock()
+	jr.items[id] = &judgeRunningItem{slave: slave}
+}
+
+func (jr *judgeRunningMap) remove(id int) {
+	jr.mutex.Lock()
+	defer jr.mutex.Unlock()
+	delete(jr.items, id)
+}
+
+func (jr *judgeRunningMap) setSlave(id int, slave gytypes.SlaveData) {
+	jr.mutex.Lock()
+	defer jr.mutex.Unlock()
+	if item, exists := jr.items[id]; exists {
+		item.slave = slave
+	}
+}
+
+func (jr *judgeRunningMap) isCancelled(id int) bool {
+	jr.mutex.Lock()
+	defer jr.mutex.Unlock()
+	if item, exists := jr.items[id]; exists {
+		return item.cancelled
+	}
+	return false
+}
+
+// Flag running submission as cancelled, returns slave currently judging it
+func (jr *judgeRunningMap) cancel(id int) (gytypes.SlaveData, bool) {
+	jr.mutex.Lock()
+	defer jr.mutex.Unlock()
+	if item, exists := jr.items[id]; exists {
+		item.cancelled = true
+		return item.slave, true
+	}
+	return gytypes.SlaveData{}, false
+}
+
+// Moving average of judging duration, used for estimating wait time
+type judgeStats struct {
+	mutex     sync.Mutex
+	avgJudge  float64 // in seconds
+	judgedNum int
+}
+
+func (js *judgeStats) record(duration time.Duration) {
+	js.mutex.Lock()
+	defer js.mutex.Unlock()
+	secs := duration.Seconds()
+	if js.judgedNum == 0 {
+		js.avgJudge = secs
+	} else {
+		js.avgJudge = js.avgJudge*0.8 + secs*0.2
+	}
+	js.judgedNum++
+}
+
+// Average judging duration in seconds, 0 if nothing judged yet
+func (js *judgeStats) average() float64 {
+	js.mutex.Lock()
+	defer js.mutex.Unlock()
+	return js.avgJudge
+}
+
+// Judging queue persisted in submissions table, so pending and in-flight
+// submissions survive master restart or crash
+type JudgeQueue struct {
+	slaveMan   *SlaveManager
+	workers    int
+	leaseTime  int64
+	retry      int
+	backoff    time.Duration
+	lowRunning int32 // Workers busy with low priority submissions
+	fairness   *judgeFairness
+	running    *judgeRunningMap
+	stats      *judgeStats
+	notify     chan bool
+	quit       chan bool
+}
+
+func MakeJudgeQueue(slaveMan *SlaveManager) JudgeQueue {
+	workers := appConfig.JudgeWorkers
+	if workers <= 0 {
+		workers = ConfigDefaultJudgeWorkers
+	}
+	leaseTime := appConfig.JudgeLease
+	if leaseTime <= 0 {
+		leaseTime = ConfigDefaultJudgeLease
+	}
+	retry := appConfig.JudgeRetry
+	if retry <= 0 {
+		retry = ConfigDefaultJudgeRetry
+	}
+	backoff := appConfig.JudgeBackoff
+	if backoff <= 0 {
+		backoff = ConfigDefaultJudgeBackoff
+	}
+	jq := JudgeQueue{
+		slaveMan:  slaveMan,
+		workers:   workers,
+		leaseTime: int64(leaseTime),
+		retry:     retry,
+		backoff:   time.Duration(backoff) * time.Millisecond,
+		fairness: &judgeFairness{
+			lastServed: make(map[string]int64),
+			byGroup:    appConfig.JudgeFairByGroup,
+		},
+		running: &judgeRunningMap{
+			items: make(map[int]*judgeRunningItem),
+		},
+		stats:  &judgeStats{},
+		notify: make(chan bool, workers),
+	}
+	return jq
+}
+
+func (jq *JudgeQueue) Start() error {
+	if jq.quit != nil {
+		return errors.New("judge queue already started")
+	}
+	db, err := OpenDatabase()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	sdm := NewSubmissionDbModel(db)
+	// No worker alive yet, so whatever still judging was left by previous run
+	count, err := sdm.RequeueExpiredSubmissions(math.MaxInt64)
+	if err != nil {
+		return err
+	}
+	log := gylib.GetStdLog()
+	if count > 0 {
+		log.Printf("Recovered %d unfinished submission(s) into judge queue", count)
+	}
+	// Double judging sampled randomly
+	rand.Seed(time.Now().UnixNano())
+	jq.quit = make(chan bool)
+	for i := 0; i < jq.workers; i++ {
+		go jq.worker(i)
+	}
+	log.Printf("Judge queue started with %d worker(s)", jq.workers)
+	// Pick up anything already queued
+	jq.Notify()
+	return nil
+}
+
+func (jq *JudgeQueue) Stop() {
+	if jq.quit == nil {
+		return
+	}
+	close(jq.quit)
+	jq.quit = nil
+}
+
+// Wake up idle worker, never blocks
+func (jq *JudgeQueue) Notify() {
+	select {
+	case jq.notify <- true:
+	default:
+	}
+}
+
+func (jq *JudgeQueue) worker(id int) {
+	quit := jq.quit
+	notify := jq.notify
+	ticker := time.NewTicker(judgeQueuePollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-quit:
+			return
+		case <-notify:
+		case <-ticker.C:
+		}
+		// Drain queue until nothing left to claim
+		for jq.dispatchNext(id) {
+			select {
+			case <-quit:
+				return
+			default:
+			}
+		}
+	}
+}
+
+// Keep a slave slot free for contestants, so mass rejudge never delays them. With single
+// slot, low priority ones still judged but only while no contestant submission queued
+func (jq *JudgeQueue) lowPriorityLimit() int32 {
+	limit := jq.getParallelism(jq.slaveMan.GetSlaves()) - 1
+	if limit < 1 {
+		limit = 1
+	}
+	return int32(limit)
+}
+
+// Get queued submissions worth trying to claim, ordered by priority. Low priority ones only
+// given if no other queued and low priority slot reserved, which must be released after judged
+func (jq *JudgeQueue) selectCandidates(queue []gytypes.JudgeQueueData) ([]gytypes.JudgeQueueData, bool) {
+	for i, item := range queue {
+		if item.Priority <= gytypes.JudgePriorityLow {
+			if i > 0 {
+				// If higher ones claimed by other workers meanwhile, retried on next round
+				return queue[:i], false
+			}
+			break
+		}
+	}
+	if (len(queue) == 0) || (queue[0].Priority > gytypes.JudgePriorityLow) {
+		return queue, false
+	}
+	if atomic.AddInt32(&jq.lowRunning, 1) > jq.lowPriorityLimit() {
+		atomic.AddInt32(&jq.lowRunning, -1)
+		return nil, false
+	}
+	return queue, true
+}
+
+// Claim and judge next queued submission, returns false if nothing processed
+func (jq *JudgeQueue) dispatchNext(workerId int) bool {
+	log := gylib.GetStdLog()
+	db, err := OpenDatabase()
+	if err != nil {
+		log.Errorf("[worker:%d] Cannot open database: %s", workerId, err.Error())
+		return false
+	}
+	defer db.Close()
+	sdm := NewSubmissionDbModel(db)
+	now := time.Now().Unix()
+	if count, err := sdm.RequeueExpiredSubmissions(now); err != nil {
+		log.Errorf("[worker:%d] Cannot requeue expired submissions: %s", workerId, err.Error())
+	} else if count > 0 {
+		log.Warnf("[worker:%d] Requeued %d submission(s) with expired lease", workerId, count)
+	}
+	queue, err := sdm.GetQueuedSubmissions()
+	if err != nil {
+		log.Errorf("[worker:%d] Cannot fetch judge queue: %s", workerId, err.Error())
+		return false
+	}
+	if len(queue) == 0 {
+		return false
+	}
+	// One submitter shouldn't monopolize slaves
+	jq.fairness.order(queue)
+	// Decided before taking slave slot, so low priority ones never occupy slot kept for contestants
+	candidates, lowReserved := jq.selectCandidates(queue)
+	if len(candidates) == 0 {
+		return false
+	}
+	if lowReserved {
+		defer atomic.AddInt32(&jq.lowRunning, -1)
+	}
+	// Leave queued until slave is available
+	sl, err := jq.slaveMan.AcquireSlave(nil)
+	if err != nil {
+		log.Warnf("[worker:%d] %s, %d submission(s) waiting", workerId, err.Error(), len(queue))
+		return false
+	}
+	for _, item := range candidates {
+		// Registered before claimed, so cancellation never missed in between
+		jq.running.add(item.SubmissionId, *sl)
+		claimed, err := sdm.ClaimQueuedSubmission(item.SubmissionId, now+jq.leaseTime)
+		if !claimed || (err != nil) {
+			jq.running.remove(item.SubmissionId)
+		}
+		if claimed && (err == nil) {
+			jq.fairness.served(item.UserId)
+			log.Printf("[worker:%d] Judging submission %d (priority %d) on slave %s", workerId, item.SubmissionId,
+				item.Priority, sl.Name)
+			jq.judgeSubmission(item, *sl, db)
+		}
+		if err != nil {
+			log.Errorf("[worker:%d] Cannot claim submission %d: %s", workerId, item.SubmissionId, err.Error())
+			break
+		}
+		if claimed {
+			return true
+		}
+	}
+	jq.slaveMan.ReleaseSlave(*sl)
+	return false
+}
+
+// Put finished submissions matching filter back into queue, returns count of submissions to be rejudged
+func (jq *JudgeQueue) Rejudge(filter gytypes.RejudgeFilter) (int, error) {
+	if filter.IsEmpty() {
+		return 0, errors.New("rejudge criteria must be specified")
+	}
+	db, err := OpenDatabase()
+	if err != nil {
+		return 0, err
+	}
+	defer db.Close()
+	sdm := NewSubmissionDbModel(db)
+	ids, err := sdm.GetSubmissionIdsForRejudge(filter)
+	if err != nil {
+		return 0, err
+	}
+	log := gylib.GetStdLog()
+	count := 0
+	for _, id := range ids {
+		if err = sdm.ResetSubmissionForRejudge(id); err != nil {
+			log.Errorf("Cannot reset submission %d for rejudge: %s", id, err.Error())
+			continue
+		}
+		jq.publishSubmission(sdm, id)
+		count++
+	}
+	log.Printf("%d submission(s) queued for rejudge", count)
+	if count > 0 {
+		jq.Notify()
+	}
+	return count, nil
+}
+
+// Tell owner about submission changed outside judging, e.g reset for rejudge
+func (jq *JudgeQueue) publishSubmission(sdm SubmissionDbModel, id int) {
+	if sub, err := sdm.GetSubmission(id); err == nil {
+		appLiveUpdates.PublishSubmission(sub)
+	}
+}
+
+// Cancel queued or running submission, running one killed on its slave
+func (jq *JudgeQueue) Cancel(id int) error {
+	db, err := OpenDatabase()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	sdm := NewSubmissionDbModel(db)
+	log := gylib.GetStdLog()
+	if cancelled, err := sdm.CancelSubmission(id, gytypes.JudgeStateQueued); err != nil {
+		return err
+	} else if cancelled {
+		log.Printf("Queued submission %d cancelled", id)
+		jq.publishSubmission(sdm, id)
+		return nil
+	}
+	if slave, running := jq.running.cancel(id); running {
+		// Judging worker concludes as cancelled even if slave unreachable
+		client, err := jq.slaveMan.OpenSlaveClient(slave)
+		if err != nil {
+			log.Warnf("Cannot reach slave %s to cancel submission %d: %s", slave.Name, id, err.Error())
+			return nil
+		}
+		defer client.Close()
+		if resp, err := client.CancelSubmission(id); err != nil {
+			log.Warnf("Cannot cancel submission %d on slave %s: %s", id, slave.Name, err.Error())
+		} else if resp.Cancelled {
+			log.Printf("Submission %d cancelled on slave %s", id, slave.Name)
+		}
+		return nil
+	}
+	// Judged by nobody alive, e.g. left by crashed master
+	if cancelled, err := sdm.CancelSubmission(id, gytypes.JudgeStateJudging); err != nil {
+		return err
+	} else if cancelled {
+		log.Printf("Orphaned submission %d cancelled", id)
+		jq.publishSubmission(sdm, id)
+		return nil
+	}
+	return errors.New("submission already finished judging")
+}
+
+// Keep lease alive while slave still working on submission
+func (jq *JudgeQueue) renewLease(id int, db DbContext, done chan bool) {
+	ticker := time.NewTicker(time.Duration(jq.leaseTime) * time.Second / 3)
+	defer ticker.Stop()
+	sdm := NewSubmissionDbModel(db)
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			if err := sdm.RenewSubmissionLease(id, time.Now().Unix()+jq.leaseTime); err != nil {
+				log := gylib.GetStdLog()
+				log.Errorf("Cannot renew lease of submission %d: %s", id, err.Error())
+			}
+		}
+	}
+}
+
+// Judge claimed submission on acquired slave, slave released after finished
+func (jq *JudgeQueue) judgeSubmission(item gytypes.JudgeQueueData, slave gytypes.SlaveData, db DbContext) {
+	sdm := NewSubmissionDbModel(db)
+	log := gylib.GetStdLog()
+	defer func() {
+		jq.running.remove(item.SubmissionId)
+		jq.slaveMan.ReleaseSlave(slave)
+		// Freed slave may serve waiting submissions
+		jq.Notify()
+	}()
+	done := make(chan bool)
+	go jq.renewLease(item.SubmissionId, db, done)
+	defer close(done)
+	sub := gytypes.SubmissionData{
+		Id:      item.SubmissionId,
+		Verdict: gytypes.SubmissionError,
+		Score:   0,
+	}
+	judgeState := gytypes.JudgeStateFailed
+	startTime := time.Now()
+	// deferred conclusion of this process
+	defer func() {
+		if judgeState == gytypes.JudgeStateDone {
+			jq.stats.record(time.Since(startTime))
+		}
+		jq.concludeSubmission(sdm, sub, judgeState, slave.Address)
+	}()
+	sbTemp, err := sdm.GetSubmission(item.SubmissionId)
+	if err != nil {
+		sub.Details = err.Error()
+		return
+	}
+	// Replace with gathered sub variable
+	sub = sbTemp
+	sub.Verdict = gytypes.SubmissionError
+	// Get Programming language data
+	lang, err := appLangPrograms.GetLanguageFromId(sub.LanguageId)
+	if err != nil {
+		sub.Details = err.Error()
+		return
+	}
+	// Get Problem details
+	cdm := NewContestDbModel(db)
+	prob, err := cdm.GetProblemById(sub.ProblemId)
+	if err != nil {
+		sub.Details = err.Error()
+		return
+	}
+	// Retrieve test case for current problem for checking
+	tests, err := sdm.GetTestCasesOfProblem(sub.ProblemId)
+	if err != nil {
+		sub.Details = err.Error()
+		return
+	}
+	// Transient slave failure retried on other slave, SE only after all attempts failed
+	excluded := make(map[string]bool)
+	backoff := jq.backoff
+	var resp gyrpc.RpcSubmissionResponse
+	for attempt := 1; ; attempt++ {
+		if !jq.running.isCancelled(sub.Id) {
+			resp, err = jq.runOnSlave(slave, sub, *lang, prob, tests)
+		}
+		// Cancellation wins even if slave managed to finish
+		if jq.running.isCancelled(sub.Id) {
+			log.Printf("[subId:%d] Judging cancelled", sub.Id)
+			sub.Verdict = gytypes.SubmissionCancelled
+			sub.Score = 0
+			sub.Details = ""
+			judgeState = gytypes.JudgeStateCancelled
+			return
+		}
+		if err == nil {
+			break
+		}
+		log.Warnf("[subId:%d] Attempt %d/%d on slave %s failed: %s", sub.Id, attempt, jq.retry, slave.Name, err.Error())
+		if attempt >= jq.retry {
+			sub.Details = err.Error()
+			return
+		}
+		excluded[slave.Address] = true
+		jq.slaveMan.MarkSlaveDown(slave)
+		time.Sleep(backoff)
+		backoff *= 2
+		if backoff > judgeMaxBackoff {
+			backoff = judgeMaxBackoff
+		}
+		if next, err := jq.slaveMan.AcquireSlave(excluded); err == nil {
+			jq.slaveMan.ReleaseSlave(slave)
+			slave = *next
+			jq.running.setSlave(sub.Id, slave)
+		} else {
+			log.Warnf("[subId:%d] %s, retrying on slave %s", sub.Id, err.Error(), slave.Name)
+		}
+	}
+	if jq.needDoubleJudge(resp.TestResults, *lang, prob) {
+		state, err := jq.doubleJudge(slave, sub, resp, *lang, prob, tests, sdm)
+		if err != nil {
+			log.Warnf("[subId:%d] Double judging skipped: %s", sub.Id, err.Error())
+		}
+		switch state {
+		case gytypes.JudgeStateCancelled:
+			log.Printf("[subId:%d] Judging cancelled", sub.Id)
+			sub.Verdict = gytypes.SubmissionCancelled
+			sub.Score = 0
+			sub.Details = ""
+			judgeState = gytypes.JudgeStateCancelled
+			return
+		case gytypes.JudgeStateHeld:
+			// Neither result published until jury decides
+			sub = resp.Submission
+			sub.Verdict = gytypes.SubmissionOnQueue
+			sub.Score = 0
+			sub.Details = "Held for jury review"
+			judgeState = gytypes.JudgeStateHeld
+			return
+		}
+	}
+	jq.storeTestResults(sdm, resp.TestResults)
+	sub = resp.Submission
+	judgeState = gytypes.JudgeStateDone
+}
+
+// Insert new rows for test case run result
+func (jq *JudgeQueue) storeTestResults(sdm SubmissionDbModel, results []gytypes.TestResultData) {
+	log := gylib.GetStdLog()
+	for _, testCase := range results {
+		if err := sdm.InsertTestResult(testCase); err == nil {
+			log.Printf("Inserting %v", testCase)
+		} else {
+			log.Errorf("TestCase insert error: %s", err.Error())
+		}
+	}
+}
+
+// Store final result of judging, then update score and notify submitter
+func (jq *JudgeQueue) concludeSubmission(sdm SubmissionDbModel, sub gytypes.SubmissionData, judgeState string, judgedBy string) {
+	log := gylib.GetStdLog()
+	if judgeState != gytypes.JudgeStateHeld {
+		if sub.Details == "" {
+			sub.Details = sub.GetStatusMessage()
+		} else {
+			sub.Details = sub.GetStatusMessage() + ": " + sub.Details
+		}
+	}
+	log.Printf("Submission [id:%d, verdict:%s]: %s", sub.Id, sub.Verdict, sub.Details)
+	if err := sdm.UpdateSubmission(sub.Id, sub); err != nil {
+		log.Errorf("Error while updating submission for id %d", sub.Id)
+		return
+	}
+	if err := sdm.SetSubmissionJudgeState(sub.Id, judgeState, judgedBy); err != nil {
+		log.Errorf("Error while updating judge state for id %d", sub.Id)
+		return
+	}
+	sub.JudgeState = judgeState
+	appLiveUpdates.PublishSubmission(sub)
+	// Score computed from judged submissions, so must be after judge state updated
+	if err := appScoreboard.UpdateUserScore(sub.ProblemId, sub.UserId); err != nil {
+		log.Errorf("Error while updating score for id %d", sub.Id)
+		return
+	}
+	desc := fmt.Sprintf("Your last submission graded as %s (%s)", sub.Verdict, sub.GetStatusMessage())
+	if judgeState == gytypes.JudgeStateHeld {
+		desc = "Your last submission is held for jury review"
+	}
+	link := "/dashboard/userViewSubmission/" + strconv.Itoa(sub.Id)
+	if err := appNotifications.AddNotification(sub.UserId, 0, desc, link); err != nil {
+		log.Errorf("Error while updating notification for id %d", sub.Id)
+		return
+	}
+}
+
+// Whether judged result should be confirmed on another slave
+func (jq *JudgeQueue) needDoubleJudge(results []gytypes.TestResultData, lang gytypes.LanguageProgramData,
+	prob gytypes.ProblemData) bool {
+	if (appConfig.JudgeDoubleRate > 0) && (rand.Float64() < appConfig.JudgeDoubleRate) {
+		return true
+	}
+	if !appConfig.JudgeDoubleNear {
+		return false
+	}
+	band := appConfig.TimingBand
+	limit := float64(lang.GetTimeLimit(prob.TimeLimit))
+	for _, tr := range results {
+		if tr.TimeElapsed >= limit*(1-band) {
+			return true
+		}
+	}
+	return false
+}
+
+// Judge again on different slave, returns held state if results disagree
+func (jq *JudgeQueue) doubleJudge(first gytypes.SlaveData, sub gytypes.SubmissionData, resp gyrpc.RpcSubmissionResponse,
+	lang gytypes.LanguageProgramData, prob gytypes.ProblemData, tests []gytypes.TestCaseData,
+	sdm SubmissionDbModel) (string, error) {
+	log := gylib.GetStdLog()
+	second, err := jq.slaveMan.AcquireSlave(map[string]bool{first.Address: true})
+	if err != nil {
+		return "", err
+	}
+	defer jq.slaveMan.ReleaseSlave(*second)
+	if second.Address == first.Address {
+		return "", errors.New("no other slave available")
+	}
+	jq.running.setSlave(sub.Id, *second)
+	log.Printf("[subId:%d] Double judging on slave %s", sub.Id, second.Name)
+	var respB gyrpc.RpcSubmissionResponse
+	if !jq.running.isCancelled(sub.Id) {
+		respB, err = jq.runOnSlave(*second, sub, lang, prob, tests)
+	}
+	if jq.running.isCancelled(sub.Id) {
+		return gytypes.JudgeStateCancelled, nil
+	}
+	if err != nil {
+		return "", err
+	}
+	subA, subB := resp.Submission, respB.Submission
+	if (subA.Verdict == subB.Verdict) && (subA.Score == subB.Score) {
+		log.Printf("[subId:%d] Double judging confirmed verdict %s", sub.Id, subA.Verdict)
+		return "", nil
+	}
+	log.Warnf("[subId:%d] Slave %s judged %s (%d) but slave %s judged %s (%d), held for jury review", sub.Id,
+		first.Name, subA.Verdict, subA.Score, second.Name, subB.Verdict, subB.Score)
+	resultA, err := json.Marshal(resp)
+	if err != nil {
+		return "", err
+	}
+	resultB, err := json.Marshal(respB)
+	if err != nil {
+		return "", err
+	}
+	md := gytypes.JudgeMismatchData{
+		SubmissionId: sub.Id,
+		SlaveA:       first.Address,
+		VerdictA:     subA.Verdict,
+		ScoreA:       subA.Score,
+		ResultA:      string(resultA),
+		SlaveB:       second.Address,
+		VerdictB:     subB.Verdict,
+		ScoreB:       subB.Score,
+		ResultB:      string(resultB),
+	}
+	if _, err = sdm.InsertJudgeMismatch(md); err != nil {
+		return "", err
+	}
+	return gytypes.JudgeStateHeld, nil
+}
+
+// Settle mismatch by accepting one of the results or judging it again
+func (jq *JudgeQueue) ResolveMismatch(id int, resolution string, resolvedBy int) error {
+	if (resolution != gytypes.MismatchAcceptA) && (resolution != gytypes.MismatchAcceptB) &&
+		(resolution != gytypes.MismatchRejudge) {
+		return errors.New("invalid mismatch resolution")
+	}
+	db, err := OpenDatabase()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	sdm := NewSubmissionDbModel(db)
+	md, err := sdm.GetJudgeMismatchById(id)
+	if err != nil {
+		return err
+	}
+	if md.Resolution != "" {
+		return errors.New("mismatch already resolved")
+	}
+	sub, err := sdm.GetSubmission(md.SubmissionId)
+	if err != nil {
+		return err
+	}
+	if sub.JudgeState != gytypes.JudgeStateHeld {
+		return errors.New("submission is not held for review")
+	}
+	if resolved, err := sdm.ResolveJudgeMismatch(id, resolution, resolvedBy); err != nil {
+		return err
+	} else if !resolved {
+		return errors.New("mismatch already resolved")
+	}
+	log := gylib.GetStdLog()
+	log.Printf("Mismatch %d of submission %d resolved as %s", id, md.SubmissionId, resolution)
+	if resolution == gytypes.MismatchRejudge {
+		if err = sdm.ResetSubmissionForRejudge(md.SubmissionId); err != nil {
+			return err
+		}
+		jq.publishSubmission(sdm, md.SubmissionId)
+		jq.Notify()
+		return nil
+	}
+	result, judgedBy := md.ResultA, md.SlaveA
+	if resolution == gytypes.MismatchAcceptB {
+		result, judgedBy = md.ResultB, md.SlaveB
+	}
+	var resp gyrpc.RpcSubmissionResponse
+	if err = json.Unmarshal([]byte(result), &resp); err != nil {
+		return err
+	}
+	jq.storeTestResults(sdm, resp.TestResults)
+	jq.concludeSubmission(sdm, resp.Submission, gytypes.JudgeStateDone, judgedBy)
+	return nil
+}
+
+func (jq *JudgeQueue) runOnSlave(slave gytypes.SlaveData, sub gytypes.SubmissionData, lang gytypes.LanguageProgramData,
+	prob gytypes.ProblemData, tests []gytypes.TestCaseData) (gyrpc.RpcSubmissionResponse, error) {
+	client, err := jq.slaveMan.OpenSlaveClient(slave)
+	if err != nil {
+		return gyrpc.RpcSubmissionResponse{}, err
+	}
+	defer client.Close()
+	// Every run time stored, so keep them fit in test result
+	rerun := appConfig.TimingRerun
+	if rerun > maxTimingRerun {
+		rerun = maxTimingRerun
+	}
+	return client.ProcessSubmission(sub, lang, prob, tests, rerun, appConfig.TimingBand)
+}
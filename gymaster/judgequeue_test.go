@@ -0,0 +1,107 @@
+package main
+
+/* GargoyleJudge - Simple Judgement System for Competitive Programming
+ * Copyright (C) Thiekus 2019
+ * Visit www.khayalan.id for updates
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/. */
+
+import (
+	"sync/atomic"
+	"testing"
+
+	"github.com/thiekus/gargoyle-judge/internal/gytypes"
+)
+
+func makeTestJudgeQueue(workers int, capacities ...int) *JudgeQueue {
+	sm := &SlaveManager{inFlight: make(map[string]int)}
+	for _, capacity := range capacities {
+		sm.slaves = append(sm.slaves, gytypes.SlaveData{
+			Enable:   true,
+			Approved: true,
+			Active:   true,
+			Capacity: capacity,
+		})
+	}
+	return &JudgeQueue{workers: workers, slaveMan: sm}
+}
+
+func makeTestQueue(priorities ...int) []gytypes.JudgeQueueData {
+	var queue []gytypes.JudgeQueueData
+	for i, priority := range priorities {
+		queue = append(queue, gytypes.JudgeQueueData{SubmissionId: i + 1, Priority: priority})
+	}
+	return queue
+}
+
+func TestLowPriorityLimit(t *testing.T) {
+	tests := []struct {
+		name       string
+		workers    int
+		capacities []int
+		want       int32
+	}{
+		{"one slave of two slots", 4, []int{2}, 1},
+		{"two slaves", 4, []int{2, 1}, 2},
+		{"bounded by workers", 2, []int{4}, 1},
+		{"unlimited slave", 4, []int{0}, 3},
+		{"single slot", 4, []int{1}, 1},
+		{"no slave", 4, nil, 1},
+	}
+	for _, tt := range tests {
+		jq := makeTestJudgeQueue(tt.workers, tt.capacities...)
+		if got := jq.lowPriorityLimit(); got != tt.want {
+			t.Errorf("%s: low priority limit = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+// Mass rejudge on one slave of capacity 2 must leave a slot for contestants
+func TestSelectCandidatesKeepsSlotForContestants(t *testing.T) {
+	jq := makeTestJudgeQueue(4, 2)
+	low := gytypes.JudgePriorityLow
+	queue := makeTestQueue(low, low, low)
+	candidates, reserved := jq.selectCandidates(queue)
+	if !reserved || (len(candidates) != 3) {
+		t.Fatalf("first low job: got %d candidate(s), reserved %v", len(candidates), reserved)
+	}
+	for i := 0; i < 2; i++ {
+		if candidates, reserved := jq.selectCandidates(queue); reserved || (len(candidates) != 0) {
+			t.Fatalf("low job while slot kept: got %d candidate(s), reserved %v", len(candidates), reserved)
+		}
+	}
+	// Contestant submission still taken while low priority slot busy
+	high := append(makeTestQueue(gytypes.JudgePriorityLow+1), queue...)
+	if candidates, reserved := jq.selectCandidates(high); reserved || (len(candidates) != 1) {
+		t.Fatalf("contestant job: got %d candidate(s), reserved %v", len(candidates), reserved)
+	}
+	// Released after judged
+	atomic.AddInt32(&jq.lowRunning, -1)
+	if _, reserved := jq.selectCandidates(queue); !reserved {
+		t.Fatal("low job not taken after previous one finished")
+	}
+}
+
+func TestSelectCandidatesSkipsLowWhileHighQueued(t *testing.T) {
+	jq := makeTestJudgeQueue(1, 1)
+	low := gytypes.JudgePriorityLow
+	queue := makeTestQueue(low+1, low+1, low)
+	candidates, reserved := jq.selectCandidates(queue)
+	if reserved || (len(candidates) != 2) {
+		t.Fatalf("got %d candidate(s), reserved %v", len(candidates), reserved)
+	}
+	for _, item := range candidates {
+		if item.Priority <= low {
+			t.Errorf("low priority submission %d offered while contestant one queued", item.SubmissionId)
+		}
+	}
+	// Single slot judges low priority one when nothing else queued
+	if _, reserved := jq.selectCandidates(makeTestQueue(low)); !reserved {
+		t.Error("low job not taken on idle single slot")
+	}
+	if atomic.LoadInt32(&jq.lowRunning) != 1 {
+		t.Errorf("low running = %d, want 1", jq.lowRunning)
+	}
+}
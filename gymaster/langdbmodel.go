@@ -26,8 +26,8 @@ func NewLanguageDbModel(db DbContext) LanguageDbModel {
 func (ldm *LanguageDbModel) GetLanguageList() (gytypes.LanguageProgramMap, error) {
 	db := ldm.db
 	query := `SELECT id, ext_name, display_name, enabled, syntax_name, source_name, exe_name, compile_cmd, exec_cmd,
-       enable_sandbox, limit_memory, limit_syscall, preg_replace_from, preg_replace_to, forbidden_keys
-       FROM {{.TablePrefix}}languages`
+       enable_sandbox, limit_memory, limit_syscall, preg_replace_from, preg_replace_to, forbidden_keys,
+       time_multiplier, time_offset, mem_overhead FROM {{.TablePrefix}}languages`
 	prep, err := db.Prepare(query)
 	if err != nil {
 		return nil, err
@@ -56,6 +56,9 @@ func (ldm *LanguageDbModel) GetLanguageList() (gytypes.LanguageProgramMap, error
 			&lp.RegexReplaceFrom,
 			&lp.RegexReplaceTo,
 			&lp.ForbiddenKeys,
+			&lp.TimeMultiplier,
+			&lp.TimeOffset,
+			&lp.MemOverhead,
 		)
 		if err != nil {
 			return nil, err
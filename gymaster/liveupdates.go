@@ -0,0 +1,314 @@
+package main
+
+/* GargoyleJudge - Simple Judgement System for Competitive Programming
+ * Copyright (C) Thiekus 2019
+ * Visit www.khayalan.id for updates
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/. */
+
+import (
+	"encoding/json"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/thiekus/gargoyle-judge/internal/gylib"
+	"github.com/thiekus/gargoyle-judge/internal/gytypes"
+)
+
+const (
+	LiveEventScoreboard = "scoreboard"
+	LiveEventSubmission = "submission"
+)
+
+const (
+	liveClientBuffer    = 16                      // Events queued per client, dropped if client too slow
+	liveScoreboardDelay = 1000 * time.Millisecond // Collect score changes before pushing scoreboard once
+	liveKeepAlive       = 30 * time.Second        // Comment sent while idle, so proxies keep stream open
+)
+
+type LiveEventData struct {
+	Name string
+	Data []byte
+}
+
+type LiveSubmissionData struct {
+	Id         int    `json:"id"`
+	ProblemId  int    `json:"problemId"`
+	Verdict    string `json:"verdict"`
+	Status     string `json:"status"`
+	JudgeState string `json:"judgeState"`
+	Score      int    `json:"score"`
+}
+
+type LiveScoreboardCell struct {
+	Class      string `json:"class"` // Same class as scoreboard page, empty if not attempted
+	Text       string `json:"text"`
+	Attempts   int    `json:"attempts"`
+	First      bool   `json:"first"`
+	GroupFirst bool   `json:"groupFirst"`
+}
+
+type LiveScoreboardRow struct {
+	UserId      int                  `json:"userId"`
+	Rank        int                  `json:"rank"`
+	TotalScore  int                  `json:"totalScore"`
+	PenaltyTime string               `json:"penaltyTime"`
+	Cells       []LiveScoreboardCell `json:"cells"`
+}
+
+// Changed rows of scoreboard, order lists user ids by rank to rearrange rows
+type LiveScoreboardDiff struct {
+	ContestId int                 `json:"contestId"`
+	Rows      []LiveScoreboardRow `json:"rows"`
+	Order     []int               `json:"order"`
+}
+
+type LiveUpdateClient struct {
+	userId    int
+	contestId int // Zero if not watching any scoreboard
+	private   bool
+	Events    chan LiveEventData
+}
+
+type liveBoardKey struct {
+	contestId int
+	private   bool
+}
+
+type liveUpdateHub struct {
+	sync.Mutex
+	clients map[*LiveUpdateClient]bool
+	boards  map[liveBoardKey][]LiveScoreboardRow // Last pushed rows of each watched scoreboard
+	pending map[int]bool                         // Contests waiting to be pushed
+	stopped bool
+}
+
+// Push scoreboard and submission changes to connected dashboard clients
+type LiveUpdateController struct {
+	hub *liveUpdateHub
+}
+
+func MakeLiveUpdateController() LiveUpdateController {
+	return LiveUpdateController{
+		hub: &liveUpdateHub{
+			clients: make(map[*LiveUpdateClient]bool),
+			boards:  make(map[liveBoardKey][]LiveScoreboardRow),
+			pending: make(map[int]bool),
+		},
+	}
+}
+
+// Register client receiving own submissions and, if contest given, scoreboard as allowed to see
+func (luc *LiveUpdateController) Subscribe(userId, contestId int, private bool) *LiveUpdateClient {
+	client := &LiveUpdateClient{
+		userId:    userId,
+		contestId: contestId,
+		private:   private,
+		Events:    make(chan LiveEventData, liveClientBuffer),
+	}
+	luc.hub.Lock()
+	defer luc.hub.Unlock()
+	if luc.hub.stopped {
+		close(client.Events)
+		return client
+	}
+	luc.hub.clients[client] = true
+	return client
+}
+
+func (luc *LiveUpdateController) Unsubscribe(client *LiveUpdateClient) {
+	luc.hub.Lock()
+	defer luc.hub.Unlock()
+	if _, exists := luc.hub.clients[client]; exists {
+		delete(luc.hub.clients, client)
+		close(client.Events)
+	}
+}
+
+// Disconnect all clients, so their streams ended before server shutdown
+func (luc *LiveUpdateController) Stop() {
+	if luc.hub == nil {
+		return
+	}
+	luc.hub.Lock()
+	defer luc.hub.Unlock()
+	luc.hub.stopped = true
+	for client := range luc.hub.clients {
+		delete(luc.hub.clients, client)
+		close(client.Events)
+	}
+}
+
+// Send event to matching clients without waiting, slow client just miss it
+func (luc *LiveUpdateController) broadcast(event LiveEventData, match func(client *LiveUpdateClient) bool) {
+	luc.hub.Lock()
+	defer luc.hub.Unlock()
+	for client := range luc.hub.clients {
+		if !match(client) {
+			continue
+		}
+		select {
+		case client.Events <- event:
+		default:
+		}
+	}
+}
+
+// Tell owner that verdict or judging state of submission changed
+func (luc *LiveUpdateController) PublishSubmission(sub gytypes.SubmissionData) {
+	if luc.hub == nil {
+		return
+	}
+	lsd := LiveSubmissionData{
+		Id:         sub.Id,
+		ProblemId:  sub.ProblemId,
+		Verdict:    sub.Verdict,
+		Status:     sub.GetStatusMessage(),
+		JudgeState: sub.JudgeState,
+		Score:      sub.Score,
+	}
+	data, err := json.Marshal(lsd)
+	if err != nil {
+		return
+	}
+	event := LiveEventData{Name: LiveEventSubmission, Data: data}
+	luc.broadcast(event, func(client *LiveUpdateClient) bool {
+		return client.userId == sub.UserId
+	})
+}
+
+// Schedule pushing scoreboard of contest, changes close in time pushed once
+func (luc *LiveUpdateController) ScoreboardChanged(contestId int) {
+	// Scoreboard also used by command line tools, without any client
+	if luc.hub == nil {
+		return
+	}
+	luc.hub.Lock()
+	defer luc.hub.Unlock()
+	if luc.hub.pending[contestId] || luc.hub.stopped {
+		return
+	}
+	luc.hub.pending[contestId] = true
+	go func() {
+		time.Sleep(liveScoreboardDelay)
+		luc.hub.Lock()
+		delete(luc.hub.pending, contestId)
+		luc.hub.Unlock()
+		luc.pushScoreboard(contestId)
+	}()
+}
+
+// Whether any client watching scoreboard of contest with given visibility
+func (luc *LiveUpdateController) hasWatcher(key liveBoardKey) bool {
+	luc.hub.Lock()
+	defer luc.hub.Unlock()
+	for client := range luc.hub.clients {
+		if (client.contestId == key.contestId) && (client.private == key.private) {
+			return true
+		}
+	}
+	// Nobody left, next watcher gets full rows
+	delete(luc.hub.boards, key)
+	return false
+}
+
+func (luc *LiveUpdateController) pushScoreboard(contestId int) {
+	log := gylib.GetStdLog()
+	for _, private := range []bool{false, true} {
+		key := liveBoardKey{contestId, private}
+		if !luc.hasWatcher(key) {
+			continue
+		}
+		// Public one stays frozen as shown on scoreboard page
+		var sb gytypes.ScoreboardData
+		var err error
+		if private {
+			sb, err = appScoreboard.GetPrivateScoreboard(contestId)
+		} else {
+			sb, err = appScoreboard.GetPublicScoreboard(contestId)
+		}
+		if err != nil {
+			log.Errorf("Cannot push scoreboard of contest %d: %s", contestId, err.Error())
+			continue
+		}
+		rows := makeLiveScoreboardRows(sb)
+		luc.hub.Lock()
+		diff := diffLiveScoreboardRows(luc.hub.boards[key], rows)
+		luc.hub.boards[key] = rows
+		luc.hub.Unlock()
+		if len(diff) == 0 {
+			continue
+		}
+		lsd := LiveScoreboardDiff{
+			ContestId: contestId,
+			Rows:      diff,
+		}
+		for _, row := range rows {
+			lsd.Order = append(lsd.Order, row.UserId)
+		}
+		data, err := json.Marshal(lsd)
+		if err != nil {
+			continue
+		}
+		event := LiveEventData{Name: LiveEventScoreboard, Data: data}
+		luc.broadcast(event, func(client *LiveUpdateClient) bool {
+			return (client.contestId == contestId) && (client.private == private)
+		})
+	}
+}
+
+// Class of problem cell, same as decided by scoreboard page
+func liveScoreboardCellClass(score gytypes.ScoreProblemData) string {
+	if score.IsAccepted {
+		if score.OneHit {
+			return "scoreboard-solved-first"
+		} else if score.GroupFirst {
+			return "scoreboard-solved-group-first"
+		}
+		return "scoreboard-solved"
+	} else if score.SubmissionCount > 0 {
+		return "scoreboard-incorrect"
+	}
+	return ""
+}
+
+func makeLiveScoreboardRows(sb gytypes.ScoreboardData) []LiveScoreboardRow {
+	var rows []LiveScoreboardRow
+	for _, cs := range sb.Contestant {
+		row := LiveScoreboardRow{
+			UserId:      cs.UserId,
+			Rank:        cs.RankNumber,
+			TotalScore:  cs.TotalScore,
+			PenaltyTime: cs.PenaltyTimeStr,
+		}
+		for _, score := range cs.Problems {
+			row.Cells = append(row.Cells, LiveScoreboardCell{
+				Class:      liveScoreboardCellClass(score),
+				Text:       score.CellText,
+				Attempts:   score.SubmissionCount,
+				First:      score.IsAccepted && score.OneHit,
+				GroupFirst: score.IsAccepted && score.GroupFirst,
+			})
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+// Get rows changed or added since last pushed rows
+func diffLiveScoreboardRows(last, current []LiveScoreboardRow) []LiveScoreboardRow {
+	lastRows := make(map[int]LiveScoreboardRow)
+	for _, row := range last {
+		lastRows[row.UserId] = row
+	}
+	var diff []LiveScoreboardRow
+	for _, row := range current {
+		if prev, exists := lastRows[row.UserId]; !exists || !reflect.DeepEqual(prev, row) {
+			diff = append(diff, row)
+		}
+	}
+	return diff
+}
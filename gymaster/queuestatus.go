@@ -0,0 +1,154 @@
+package main
+
+/* GargoyleJudge - Simple Judgement System for Competitive Programming
+ * Copyright (C) Thiekus 2019
+ * Visit www.khayalan.id for updates
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/. */
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/thiekus/gargoyle-judge/internal/gytypes"
+)
+
+type QueuePendingDetails struct {
+	SubmissionId  int    `json:"submissionId"`
+	ProblemName   string `json:"problemName"`
+	ContestName   string `json:"contestName"`
+	SubmitTimeStr string `json:"submitTimeStr"`
+	JudgeState    string `json:"judgeState"`
+	Position      int    `json:"position"`      // 0 if being judged
+	EstimatedWait int64  `json:"estimatedWait"` // in seconds, -1 if unknown
+	WaitStr       string `json:"waitStr"`
+}
+
+type QueueSlaveDetails struct {
+	Name     string  `json:"name"`
+	Address  string  `json:"address,omitempty"`
+	Active   bool    `json:"active"`
+	Busy     bool    `json:"busy"`
+	InFlight int     `json:"inFlight,omitempty"`
+	Capacity int     `json:"capacity,omitempty"`
+	Latency  float64 `json:"latency,omitempty"`
+}
+
+type QueueStatusDetails struct {
+	Detailed     bool                  `json:"detailed"`
+	QueueLength  int                   `json:"queueLength"`
+	JudgingCount int                   `json:"judgingCount"`
+	AverageJudge float64               `json:"averageJudge"` // in seconds
+	Pending      []QueuePendingDetails `json:"pending"`
+	Slaves       []QueueSlaveDetails   `json:"slaves"`
+	UpdateTime   int64                 `json:"updateTime"`
+}
+
+func formatQueueWait(judgeState string, wait int64) string {
+	if judgeState == gytypes.JudgeStateJudging {
+		return "Being judged"
+	}
+	if judgeState == gytypes.JudgeStateHeld {
+		return "Held for review"
+	}
+	if wait < 0 {
+		return "Unknown"
+	}
+	return "~" + (time.Duration(wait) * time.Second).String()
+}
+
+// Count of submissions able to be judged at once by active slaves
+func (jq *JudgeQueue) getParallelism(slaves []gytypes.SlaveData) int {
+	slots := 0
+	for _, sl := range slaves {
+		if !sl.Enable || !sl.Approved || !sl.Active {
+			continue
+		}
+		// Unlimited slave bounded by workers anyway
+		if sl.Capacity <= 0 {
+			return jq.workers
+		}
+		slots += sl.Capacity
+	}
+	if slots > jq.workers {
+		slots = jq.workers
+	}
+	return slots
+}
+
+// Get queue status for user, slave details only shown for jury
+func (jq *JudgeQueue) GetStatus(userId int, detailed bool) (QueueStatusDetails, error) {
+	status := QueueStatusDetails{
+		Detailed:   detailed,
+		UpdateTime: time.Now().Unix(),
+	}
+	db, err := OpenDatabase()
+	if err != nil {
+		return status, err
+	}
+	defer db.Close()
+	sdm := NewSubmissionDbModel(db)
+	queue, err := sdm.GetQueuedSubmissions()
+	if err != nil {
+		return status, err
+	}
+	// Same order as dispatcher would pick
+	jq.fairness.order(queue)
+	status.QueueLength = len(queue)
+	if status.JudgingCount, err = sdm.GetJudgeStateCount(gytypes.JudgeStateJudging); err != nil {
+		return status, err
+	}
+	positions := make(map[int]int)
+	for i, item := range queue {
+		positions[item.SubmissionId] = i + 1
+	}
+	slaves := jq.slaveMan.GetSlaves()
+	parallel := jq.getParallelism(slaves)
+	status.AverageJudge = jq.stats.average()
+	pending, err := sdm.GetPendingSubmissionsOfUser(userId)
+	if err != nil {
+		return status, err
+	}
+	for _, sub := range pending {
+		pd := QueuePendingDetails{
+			SubmissionId:  sub.Id,
+			ProblemName:   sub.ProblemName,
+			ContestName:   sub.ContestName,
+			SubmitTimeStr: sub.SubmitTime.Format("2006-01-02 15:04:05"),
+			JudgeState:    sub.JudgeState,
+			Position:      positions[sub.Id],
+			EstimatedWait: -1,
+		}
+		if (sub.JudgeState == gytypes.JudgeStateJudging) || (sub.JudgeState == gytypes.JudgeStateHeld) {
+			pd.EstimatedWait = 0
+		} else if (parallel > 0) && (status.AverageJudge > 0) && (pd.Position > 0) {
+			// Judged in batches as wide as available slots
+			rounds := math.Ceil(float64(pd.Position) / float64(parallel))
+			pd.EstimatedWait = int64(math.Ceil(rounds * status.AverageJudge))
+		}
+		pd.WaitStr = formatQueueWait(pd.JudgeState, pd.EstimatedWait)
+		status.Pending = append(status.Pending, pd)
+	}
+	for i, sl := range slaves {
+		if !sl.Enable || !sl.Approved {
+			continue
+		}
+		sd := QueueSlaveDetails{
+			Name:   fmt.Sprintf("Slave #%d", i+1),
+			Active: sl.Active,
+			Busy:   sl.InFlight > 0,
+		}
+		if detailed {
+			sd.Name = sl.Name
+			sd.Address = sl.Address
+			sd.InFlight = sl.InFlight
+			sd.Capacity = sl.Capacity
+			sd.Latency = sl.Latency
+		}
+		status.Slaves = append(status.Slaves, sd)
+	}
+	return status, nil
+}
@@ -18,6 +18,12 @@ import (
 	"github.com/thiekus/gargoyle-judge/internal/gytypes"
 )
 
+// Identify score cell of user on a problem
+type scoreCellKey struct {
+	userId    int
+	problemId int
+}
+
 type ScoreDbModel struct {
 	db DbContext
 }
@@ -34,7 +40,7 @@ func (sdm *ScoreDbModel) GetContestInfoById(contestId int) (gytypes.ScoreContest
 	db := sdm.db
 	// First, query from contest info
 	query := `SELECT c.id, c.title, c.style, c.enable_freeze, c.freeze_timestamp, c.unfreeze_timestamp, c.allow_public,
-        c.start_timestamp, c.penalty_time FROM {{.TablePrefix}}contests as c WHERE c.id = ?`
+        c.start_timestamp, c.penalty_time, c.unfrozen FROM {{.TablePrefix}}contests as c WHERE c.id = ?`
 	stmt, err := db.Prepare(query)
 	if err != nil {
 		return scd, err
@@ -52,6 +58,7 @@ func (sdm *ScoreDbModel) GetContestInfoById(contestId int) (gytypes.ScoreContest
 		&scd.AllowPublic,
 		&utStartTime,
 		&scd.PenaltyTime,
+		&scd.Unfrozen,
 	)
 	if err != nil {
 		return scd, err
@@ -67,7 +74,7 @@ func (sdm *ScoreDbModel) GetContestInfoByProblemId(problemId int) (gytypes.Score
 	db := sdm.db
 	// First, query from contest info
 	query := `SELECT c.id, c.title, c.style, c.enable_freeze, c.freeze_timestamp, c.unfreeze_timestamp, c.allow_public,
-        c.start_timestamp, c.penalty_time FROM {{.TablePrefix}}problems as p INNER JOIN
+        c.start_timestamp, c.penalty_time, c.unfrozen FROM {{.TablePrefix}}problems as p INNER JOIN
         {{.TablePrefix}}contests as c ON c.id = p.contest_id WHERE p.id = ?`
 	stmt, err := db.Prepare(query)
 	if err != nil {
@@ -86,6 +93,7 @@ func (sdm *ScoreDbModel) GetContestInfoByProblemId(problemId int) (gytypes.Score
 		&scd.AllowPublic,
 		&utStartTime,
 		&scd.PenaltyTime,
+		&scd.Unfrozen,
 	)
 	if err != nil {
 		return scd, err
@@ -103,8 +111,9 @@ func (sdm *ScoreDbModel) GetScoreboardForContest(contestId int, publicBoard bool
 	if err != nil {
 		return nil, err
 	}
-	if (sci.Style != gytypes.ScoreStyleICPC) && (sci.Style != gytypes.ScoreStyleIOI) {
-		return nil, errors.New("unsupported contest style")
+	style, err := GetContestStyle(sci.Style)
+	if err != nil {
+		return nil, err
 	}
 	if (publicBoard) && (!sci.AllowPublic) {
 		return nil, errors.New("public scoreboard not allowed")
@@ -218,6 +227,8 @@ func (sdm *ScoreDbModel) GetScoreboardForContest(contestId int, publicBoard bool
 	if err != nil {
 		return nil, err
 	}
+	// Solved cells with their absolute accepted time, to find first solvers of each group
+	var solvedCells []scoreSolvedCell
 	for rowsScore.Next() {
 		score := gytypes.ScoreProblemData{}
 		err = rowsScore.Scan(
@@ -234,34 +245,26 @@ func (sdm *ScoreDbModel) GetScoreboardForContest(contestId int, publicBoard bool
 		if err != nil {
 			return nil, err
 		}
-		score.IsAccepted = score.AcceptedTime > 0
+		score.IsAccepted = style.IsSolved(score)
 		// Score comparison is unlikely needed as filtered by SQL, but won't we paranoid?
 		if score.ContestId == contestId {
 			if user, exists := users[score.UserId]; exists {
 				if problemIndex, exists := contestProblemsId[score.ProblemId]; exists {
 					user.TotalScore += score.Score
-					// On ICPC, penalty time considered to
-					if sci.Style == gytypes.ScoreStyleICPC {
-						user.TotalPenaltyTime += score.PenaltyTime
+					user.AttemptCount += score.SubmissionCount
+					if score.IsAccepted {
+						user.SolvedCount++
 						if score.AcceptedTime > 0 {
-							submitPenaltyTime := int64(0)
-							contestStartTime := sci.StartTimestamp.Unix()
-							// Is contest was defined time?
-							if contestStartTime > 0 {
-								// Use fixed contest time
-								submitPenaltyTime = score.AcceptedTime - contestStartTime
-							} else {
-								// For unlimited contest, use first which user enter instead
-								submitPenaltyTime = score.AcceptedTime - user.StartTime
-							}
-							if submitPenaltyTime > 0 {
-								user.TotalPenaltyTime += submitPenaltyTime
-							}
+							solvedCells = append(solvedCells, scoreSolvedCell{
+								userId:       user.UserId,
+								problemIndex: problemIndex,
+								acceptedTime: score.AcceptedTime,
+							})
 						}
 					}
 					// Delta of UTC (e.g UTC +7)
 					//utcDelta := int64(math.RoundToEven(appConfig.TimeUTC * 3600))
-					if score.IsAccepted {
+					if score.AcceptedTime > 0 {
 						contestStartTime := sci.StartTimestamp.Unix()
 						// Is contest was defined time?
 						if contestStartTime > 0 {
@@ -273,9 +276,20 @@ func (sdm *ScoreDbModel) GetScoreboardForContest(contestId int, publicBoard bool
 						}
 						// Don't alter with local time, use UTC
 						score.AcceptedTimeStr = gylib.TimeToHMS(time.Unix(score.AcceptedTime, 0).UTC())
+						if score.AcceptedTime > user.LastScoreTime {
+							user.LastScoreTime = score.AcceptedTime
+						}
+					}
+					score.CellText = style.ProblemCell(score)
+					if score.OneHit {
+						// Ties keep lowest user id, so stays same on every refresh
+						cp := &contestProblems[problemIndex]
+						if (cp.FirstSolverId == 0) || (score.UserId < cp.FirstSolverId) {
+							cp.FirstSolverId = user.UserId
+							cp.FirstSolverName = user.Name
+							cp.FirstSolveTimeStr = score.AcceptedTimeStr
+						}
 					}
-					// Don't alter with local time, use UTC
-					user.PenaltyTimeStr = gylib.TimeToHMS(time.Unix(user.TotalPenaltyTime, 0).UTC())
 					// Replace again with modified user info
 					user.Problems[problemIndex] = score
 					users[user.UserId] = user
@@ -283,21 +297,30 @@ func (sdm *ScoreDbModel) GetScoreboardForContest(contestId int, publicBoard bool
 			}
 		}
 	}
+	if appConfig.FirstSolveGroup {
+		groups, err := sdm.GetContestantGroups(contestId)
+		if err != nil {
+			return nil, err
+		}
+		markGroupFirstSolvers(users, groups, solvedCells)
+	}
 	// Contestant as to be sorted user by rank
 	var contestants gytypes.ScoreContestantDataList
 	for _, cs := range users {
+		// Penalty depends on style, computed after all problem scores known
+		cs.TotalPenaltyTime = style.ContestantPenalty(sci, cs)
+		// Don't alter with local time, use UTC
+		cs.PenaltyTimeStr = gylib.TimeToHMS(time.Unix(cs.TotalPenaltyTime, 0).UTC())
 		contestants = append(contestants, cs)
 	}
-	// See scoreinfo.go in gytypes how sort interface method was implemented
-	sort.Sort(contestants)
-	for i := range contestants {
-		contestants[i].RankNumber = i + 1
-	}
+	// See conteststyle.go how contestants ranked for each style
+	rankContestants(contestants, style)
 	// Done!
 	sb := gytypes.ScoreboardData{
 		ContestId:       contestId,
 		ContestName:     sci.Title,
 		ContestStyle:    sci.Style,
+		ShowPenalty:     style.HasPenalty(),
 		ContestantCount: contestantCount,
 		Problems:        contestProblems,
 		Contestant:      contestants,
@@ -306,6 +329,45 @@ func (sdm *ScoreDbModel) GetScoreboardForContest(contestId int, publicBoard bool
 	return &sb, nil
 }
 
+// Solved score cell of contestant on scoreboard
+type scoreSolvedCell struct {
+	userId       int
+	problemIndex int
+	acceptedTime int64
+}
+
+// Mark earliest solvers of each problem within same group, contestants without group are skipped.
+// Unlike one_hit this is derived on every render and never stored, so it follows group membership
+// and rejudges freely. Therefore jury never notified of it, only of one_hit.
+func markGroupFirstSolvers(users map[int]gytypes.ScoreContestantData, groups map[int]int,
+	solvedCells []scoreSolvedCell) {
+	type groupProblemKey struct {
+		groupId      int
+		problemIndex int
+	}
+	firstTimes := make(map[groupProblemKey]int64)
+	for _, cell := range solvedCells {
+		groupId, exists := groups[cell.userId]
+		if !exists {
+			continue
+		}
+		key := groupProblemKey{groupId, cell.problemIndex}
+		if first, exists := firstTimes[key]; !exists || (cell.acceptedTime < first) {
+			firstTimes[key] = cell.acceptedTime
+		}
+	}
+	for _, cell := range solvedCells {
+		groupId, exists := groups[cell.userId]
+		if !exists {
+			continue
+		}
+		if firstTimes[groupProblemKey{groupId, cell.problemIndex}] == cell.acceptedTime {
+			// Problems slice shared with map entry, so no need to store user again
+			users[cell.userId].Problems[cell.problemIndex].GroupFirst = true
+		}
+	}
+}
+
 func (sdm *ScoreDbModel) GetProblemScoreByUser(contestId, problemId, userId int, publicScoreboard bool) (*gytypes.ScoreProblemData, error) {
 	db := sdm.db
 	selTable := "{{.TablePrefix}}scores_private"
@@ -338,79 +400,187 @@ func (sdm *ScoreDbModel) GetProblemScoreByUser(contestId, problemId, userId int,
 	return &problem, nil
 }
 
-func (sdm *ScoreDbModel) SubmitIntoScoreboard(problemId, userId, score int, accepted bool) error {
+// Whether verdict counted as attempt on scoreboard
+func isScoredVerdict(verdict string) bool {
+	return (verdict != gytypes.SubmissionCompilerError) && (verdict != gytypes.SubmissionOnQueue) &&
+		(verdict != gytypes.SubmissionError)
+}
+
+// Compute score cell from judged submissions before cutoff time (0 means no cutoff),
+// returns false if nothing counted
+func (sdm *ScoreDbModel) computeProblemScore(sci gytypes.ScoreContestInfo, score *gytypes.ScoreProblemData,
+	history []gytypes.ScoreSubmissionData, cutoff int64) (bool, error) {
+	style, err := GetContestStyle(sci.Style)
+	if err != nil {
+		return false, err
+	}
+	score.Score = 0
+	score.AcceptedTime = 0
+	score.PenaltyTime = 0
+	score.SubmissionCount = 0
+	score.Regraded = false
+	var counted []gytypes.ScoreSubmissionData
+	for _, sub := range history {
+		if (cutoff > 0) && (sub.SubmitTime >= cutoff) {
+			break
+		}
+		if isScoredVerdict(sub.Verdict) {
+			counted = append(counted, sub)
+		}
+	}
+	style.AccumulateScore(sci, score, counted)
+	return score.SubmissionCount > 0, nil
+}
+
+// Recompute private and public score of user on a problem from its submissions history,
+// returns users just become first solver of that problem on private scoreboard
+func (sdm *ScoreDbModel) RecomputeProblemScore(problemId, userId int) ([]int, error) {
 	sci, err := sdm.GetContestInfoByProblemId(problemId)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	err = sdm.processSubmitIntoScoreboard(sci, sci.ContestId, problemId, userId, score, accepted, false)
+	subm := NewSubmissionDbModel(sdm.db)
+	history, err := subm.GetJudgedSubmissionsOfUser(problemId, userId)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	ft := sci.FreezeTime.Unix()
-	// TODO: remove EnableFreeze as doesn't matter
-	if ft > 0 {
-		if time.Now().Unix() < ft {
-			err = sdm.processSubmitIntoScoreboard(sci, sci.ContestId, problemId, userId, score, accepted, true)
-		}
-	} else {
-		err = sdm.processSubmitIntoScoreboard(sci, sci.ContestId, problemId, userId, score, accepted, true)
+	if err = sdm.storeProblemScore(sci, problemId, userId, history, false); err != nil {
+		return nil, err
 	}
-	return err
+	firstSolvers, err := sdm.refreshFirstSolvers(sci, problemId, false)
+	if err != nil {
+		return nil, err
+	}
+	if err = sdm.storeProblemScore(sci, problemId, userId, history, true); err != nil {
+		return nil, err
+	}
+	if _, err = sdm.refreshFirstSolvers(sci, problemId, true); err != nil {
+		return nil, err
+	}
+	return firstSolvers, nil
 }
 
-func (sdm *ScoreDbModel) processSubmitIntoScoreboard(sci gytypes.ScoreContestInfo, contestId, problemId, userId, score int, accepted, publicScoreboard bool) error {
-	// Since ICPC doesn't matter what you write out
-	if sci.Style == gytypes.ScoreStyleICPC {
-		if score >= 100 {
-			score = 1
-		} else {
-			score = 0
-		}
-	}
-	// Fetch score data if exists
-	if currentScore, err := sdm.GetProblemScoreByUser(contestId, problemId, userId, publicScoreboard); err == nil {
-		// Score entry exists, just update that
-		if sci.Style == gytypes.ScoreStyleICPC {
-			// Ignore any submission if current problem was solved
-			if currentScore.AcceptedTime > 0 {
-				return nil
-			}
+// Mark earliest solvers of a problem as first to solve (all of them when solved at same time),
+// returns users newly marked
+func (sdm *ScoreDbModel) refreshFirstSolvers(sci gytypes.ScoreContestInfo, problemId int,
+	publicScoreboard bool) ([]int, error) {
+	style, err := GetContestStyle(sci.Style)
+	if err != nil {
+		return nil, err
+	}
+	scores, err := sdm.GetScoresOfContest(sci.ContestId, publicScoreboard)
+	if err != nil {
+		return nil, err
+	}
+	var firstTime int64 = 0
+	for _, score := range scores {
+		if (score.ProblemId != problemId) || (score.AcceptedTime <= 0) || !style.IsSolved(score) {
+			continue
+		}
+		if (firstTime == 0) || (score.AcceptedTime < firstTime) {
+			firstTime = score.AcceptedTime
+		}
+	}
+	var marked []int
+	for _, score := range scores {
+		if score.ProblemId != problemId {
+			continue
 		}
-		currentScore.Score = score
-		currentScore.SubmissionCount++
-		if accepted {
-			currentScore.AcceptedTime = time.Now().Unix()
-		} else {
-			currentScore.PenaltyTime += sci.PenaltyTime
+		oneHit := (firstTime > 0) && (score.AcceptedTime == firstTime) && style.IsSolved(score)
+		if oneHit == score.OneHit {
+			continue
+		}
+		score.OneHit = oneHit
+		if err = sdm.UpdateScore(&score, publicScoreboard); err != nil {
+			return nil, err
+		}
+		if oneHit {
+			marked = append(marked, score.UserId)
+		}
+	}
+	return marked, nil
+}
+
+// Get first group of each contestant of contest, for contestants being member of any group
+func (sdm *ScoreDbModel) GetContestantGroups(contestId int) (map[int]int, error) {
+	db := sdm.db
+	query := `SELECT gm.user_id, MIN(gm.group_id) FROM {{.TablePrefix}}group_members as gm
+        INNER JOIN {{.TablePrefix}}contest_access as a ON a.id_user = gm.user_id
+        WHERE a.id_contest = ? GROUP BY gm.user_id`
+	stmt, err := db.Prepare(query)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+	rows, err := stmt.Query(contestId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	groups := make(map[int]int)
+	for rows.Next() {
+		var userId, groupId int
+		if err = rows.Scan(&userId, &groupId); err != nil {
+			return nil, err
 		}
-		// Update here
-		if err = sdm.UpdateScore(currentScore, publicScoreboard); err != nil {
+		groups[userId] = groupId
+	}
+	return groups, nil
+}
+
+// Get time public scoreboard frozen at (0 means never frozen)
+// EnableFreeze doesn't matter, freeze time alone decides as public scoreboard always did
+func getScoreFreezeTime(sci gytypes.ScoreContestInfo) int64 {
+	if freezeTime := sci.FreezeTime.Unix(); freezeTime > 0 {
+		return freezeTime
+	}
+	return 0
+}
+
+// Get submission time limit of counted submissions on a scoreboard (0 means no cutoff)
+func (sdm *ScoreDbModel) getScoreCutoff(sci gytypes.ScoreContestInfo, publicScoreboard bool) int64 {
+	if !publicScoreboard {
+		return 0
+	}
+	// Public scoreboard stops at freeze time, until unfrozen
+	if sci.Unfrozen {
+		return 0
+	}
+	return getScoreFreezeTime(sci)
+}
+
+func (sdm *ScoreDbModel) storeProblemScore(sci gytypes.ScoreContestInfo, problemId, userId int,
+	history []gytypes.ScoreSubmissionData, publicScoreboard bool) error {
+	cutoff := sdm.getScoreCutoff(sci, publicScoreboard)
+	if publicScoreboard && (cutoff > 0) {
+		// Revealed by resolver, so public one no longer frozen
+		if revealed, err := sdm.IsScoreRevealed(sci.ContestId, problemId, userId); err != nil {
 			return err
+		} else if revealed {
+			cutoff = 0
 		}
-	} else {
-		// Seems new submission, so we insert as new entry
-		spd := gytypes.ScoreProblemData{
-			ContestId:       contestId,
-			ProblemId:       problemId,
-			UserId:          userId,
-			Score:           score,
-			AcceptedTime:    0,
-			PenaltyTime:     0,
-			SubmissionCount: 1,
-			OneHit:          false,
-			Regraded:        false,
-		}
-		if accepted {
-			spd.AcceptedTime = time.Now().Unix()
-		} else {
-			spd.PenaltyTime = sci.PenaltyTime
-		}
-		if err = sdm.InsertScore(&spd, publicScoreboard); err != nil {
+	}
+	if currentScore, err := sdm.GetProblemScoreByUser(sci.ContestId, problemId, userId, publicScoreboard); err == nil {
+		// Score entry exists, just update that, or remove if nothing left to count
+		counted, err := sdm.computeProblemScore(sci, currentScore, history, cutoff)
+		if err != nil {
 			return err
 		}
+		if !counted {
+			return sdm.DeleteScore(currentScore, publicScoreboard)
+		}
+		return sdm.UpdateScore(currentScore, publicScoreboard)
 	}
-	return nil
+	spd := gytypes.ScoreProblemData{
+		ContestId: sci.ContestId,
+		ProblemId: problemId,
+		UserId:    userId,
+		OneHit:    false,
+	}
+	if counted, err := sdm.computeProblemScore(sci, &spd, history, cutoff); (err != nil) || !counted {
+		return err
+	}
+	return sdm.InsertScore(&spd, publicScoreboard)
 }
 
 func (sdm *ScoreDbModel) InsertScore(score *gytypes.ScoreProblemData, publicScoreboard bool) error {
@@ -475,3 +645,349 @@ func (sdm *ScoreDbModel) UpdateScore(score *gytypes.ScoreProblemData, publicScor
 	)
 	return err
 }
+
+func (sdm *ScoreDbModel) DeleteScore(score *gytypes.ScoreProblemData, publicScoreboard bool) error {
+	db := sdm.db
+	selTable := "{{.TablePrefix}}scores_private"
+	if publicScoreboard {
+		selTable = "{{.TablePrefix}}scores_public"
+	}
+	query := `DELETE FROM %s WHERE (id_contest = ?) AND (id_problem = ?) AND (id_user = ?)`
+	query = fmt.Sprintf(query, selTable)
+	stmt, err := db.Prepare(query)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	_, err = stmt.Exec(score.ContestId, score.ProblemId, score.UserId)
+	return err
+}
+
+func (sdm *ScoreDbModel) GetScoresOfContest(contestId int, publicScoreboard bool) ([]gytypes.ScoreProblemData, error) {
+	db := sdm.db
+	selTable := "{{.TablePrefix}}scores_private"
+	if publicScoreboard {
+		selTable = "{{.TablePrefix}}scores_public"
+	}
+	query := `SELECT id_contest, id_problem, id_user, score, accepted_time, penalty_time, submission_count,
+        one_hit, regraded FROM %s WHERE id_contest = ? ORDER BY id_user ASC, id_problem ASC`
+	query = fmt.Sprintf(query, selTable)
+	stmt, err := db.Prepare(query)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+	rows, err := stmt.Query(contestId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var scores []gytypes.ScoreProblemData
+	for rows.Next() {
+		score := gytypes.ScoreProblemData{}
+		err = rows.Scan(
+			&score.ContestId,
+			&score.ProblemId,
+			&score.UserId,
+			&score.Score,
+			&score.AcceptedTime,
+			&score.PenaltyTime,
+			&score.SubmissionCount,
+			&score.OneHit,
+			&score.Regraded,
+		)
+		if err != nil {
+			return nil, err
+		}
+		scores = append(scores, score)
+	}
+	return scores, nil
+}
+
+// Whether both score cells having same computed values
+func isSameProblemScore(a, b gytypes.ScoreProblemData) bool {
+	return (a.Score == b.Score) && (a.AcceptedTime == b.AcceptedTime) && (a.PenaltyTime == b.PenaltyTime) &&
+		(a.SubmissionCount == b.SubmissionCount) && (a.Regraded == b.Regraded)
+}
+
+// Recompute all scores of contest from submissions history and compare with stored ones,
+// nothing written until plan applied by ApplyContestRebuild
+func (sdm *ScoreDbModel) PlanContestRebuild(contestId int) (gytypes.ScoreRebuildPlan, error) {
+	plan := gytypes.ScoreRebuildPlan{ContestId: contestId}
+	sci, err := sdm.GetContestInfoById(contestId)
+	if err != nil {
+		return plan, err
+	}
+	plan.ContestName = sci.Title
+	subm := NewSubmissionDbModel(sdm.db)
+	subs, err := subm.GetJudgedSubmissionsOfContest(contestId)
+	if err != nil {
+		return plan, err
+	}
+	revealed, err := sdm.GetRevealedScores(contestId)
+	if err != nil {
+		return plan, err
+	}
+	// Group history by user and problem while preserving submission order
+	var keys []scoreCellKey
+	histories := make(map[scoreCellKey][]gytypes.ScoreSubmissionData)
+	for _, sub := range subs {
+		key := scoreCellKey{sub.UserId, sub.ProblemId}
+		if _, exists := histories[key]; !exists {
+			keys = append(keys, key)
+		}
+		histories[key] = append(histories[key], sub)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].userId != keys[j].userId {
+			return keys[i].userId < keys[j].userId
+		}
+		return keys[i].problemId < keys[j].problemId
+	})
+	// Names only for displaying differences
+	cdm := NewContestDbModel(sdm.db)
+	problems, err := cdm.GetProblemSet(contestId)
+	if err != nil {
+		return plan, err
+	}
+	problemNames := make(map[int]string)
+	for _, prob := range problems {
+		problemNames[prob.Id] = prob.ShortName + " - " + prob.Name
+	}
+	udm := NewUserDbModel(sdm.db)
+	users, err := udm.GetUserList()
+	if err != nil {
+		return plan, err
+	}
+	userNames := make(map[int]string)
+	for _, user := range users {
+		userNames[user.Id] = user.Username
+	}
+	for _, publicScoreboard := range []bool{false, true} {
+		cutoff := sdm.getScoreCutoff(sci, publicScoreboard)
+		stored, err := sdm.GetScoresOfContest(contestId, publicScoreboard)
+		if err != nil {
+			return plan, err
+		}
+		current := make(map[scoreCellKey]gytypes.ScoreProblemData)
+		for _, score := range stored {
+			current[scoreCellKey{score.UserId, score.ProblemId}] = score
+		}
+		for _, key := range keys {
+			cur, exists := current[key]
+			delete(current, key)
+			rebuilt := gytypes.ScoreProblemData{
+				ContestId: contestId,
+				ProblemId: key.problemId,
+				UserId:    key.userId,
+			}
+			if exists {
+				rebuilt = cur
+			}
+			cellCutoff := cutoff
+			if publicScoreboard && revealed[key] {
+				cellCutoff = 0
+			}
+			counted, err := sdm.computeProblemScore(sci, &rebuilt, histories[key], cellCutoff)
+			if err != nil {
+				return plan, err
+			}
+			diff := gytypes.ScoreRebuildDiff{
+				Public:      publicScoreboard,
+				UserName:    userNames[key.userId],
+				ProblemName: problemNames[key.problemId],
+				Current:     cur,
+				Rebuilt:     rebuilt,
+			}
+			if !exists && counted {
+				diff.Action = gytypes.ScoreRebuildInsert
+			} else if exists && !counted {
+				diff.Action = gytypes.ScoreRebuildDelete
+			} else if exists && !isSameProblemScore(cur, rebuilt) {
+				diff.Action = gytypes.ScoreRebuildUpdate
+			} else {
+				continue
+			}
+			plan.Diffs = append(plan.Diffs, diff)
+		}
+		// Anything left has no judged submission at all
+		for _, score := range stored {
+			key := scoreCellKey{score.UserId, score.ProblemId}
+			if _, exists := current[key]; exists {
+				plan.Diffs = append(plan.Diffs, gytypes.ScoreRebuildDiff{
+					Action:      gytypes.ScoreRebuildDelete,
+					Public:      publicScoreboard,
+					UserName:    userNames[key.userId],
+					ProblemName: problemNames[key.problemId],
+					Current:     score,
+				})
+			}
+		}
+	}
+	return plan, nil
+}
+
+// Write differences found by PlanContestRebuild into score tables
+func (sdm *ScoreDbModel) ApplyContestRebuild(plan gytypes.ScoreRebuildPlan) error {
+	// First solvers of changed problems marked again after all written
+	changed := make(map[int]bool)
+	for _, diff := range plan.Diffs {
+		changed[diff.Current.ProblemId] = true
+		changed[diff.Rebuilt.ProblemId] = true
+		var err error
+		switch diff.Action {
+		case gytypes.ScoreRebuildInsert:
+			err = sdm.InsertScore(&diff.Rebuilt, diff.Public)
+		case gytypes.ScoreRebuildUpdate:
+			err = sdm.UpdateScore(&diff.Rebuilt, diff.Public)
+		case gytypes.ScoreRebuildDelete:
+			err = sdm.DeleteScore(&diff.Current, diff.Public)
+		default:
+			err = fmt.Errorf("unknown rebuild action %s", diff.Action)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	delete(changed, 0)
+	if len(changed) == 0 {
+		return nil
+	}
+	sci, err := sdm.GetContestInfoById(plan.ContestId)
+	if err != nil {
+		return err
+	}
+	for problemId := range changed {
+		for _, publicScoreboard := range []bool{false, true} {
+			if _, err = sdm.refreshFirstSolvers(sci, problemId, publicScoreboard); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Get score cells of contest already revealed on public scoreboard
+func (sdm *ScoreDbModel) GetRevealedScores(contestId int) (map[scoreCellKey]bool, error) {
+	db := sdm.db
+	query := `SELECT id_user, id_problem FROM {{.TablePrefix}}score_reveals WHERE id_contest = ?`
+	stmt, err := db.Prepare(query)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+	rows, err := stmt.Query(contestId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	revealed := make(map[scoreCellKey]bool)
+	for rows.Next() {
+		var key scoreCellKey
+		if err = rows.Scan(&key.userId, &key.problemId); err != nil {
+			return nil, err
+		}
+		revealed[key] = true
+	}
+	return revealed, nil
+}
+
+func (sdm *ScoreDbModel) IsScoreRevealed(contestId, problemId, userId int) (bool, error) {
+	db := sdm.db
+	query := `SELECT COUNT(*) FROM {{.TablePrefix}}score_reveals WHERE (id_contest = ?) AND (id_problem = ?) AND (id_user = ?)`
+	stmt, err := db.Prepare(query)
+	if err != nil {
+		return false, err
+	}
+	defer stmt.Close()
+	count := 0
+	if err = stmt.QueryRow(contestId, problemId, userId).Scan(&count); err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// Reveal frozen score cell, public score recomputed without freeze afterwards
+func (sdm *ScoreDbModel) RevealScore(contestId, problemId, userId, revealedBy int) error {
+	db := sdm.db
+	query := `INSERT INTO {{.TablePrefix}}score_reveals (id_contest, id_problem, id_user, reveal_time, revealed_by)
+        VALUES (?, ?, ?, ?, ?)`
+	stmt, err := db.Prepare(query)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	if _, err = stmt.Exec(contestId, problemId, userId, time.Now().Unix(), revealedBy); err != nil {
+		return err
+	}
+	_, err = sdm.RecomputeProblemScore(problemId, userId)
+	return err
+}
+
+// Get contests which unfreeze time passed but public scoreboard still frozen
+func (sdm *ScoreDbModel) GetContestsToUnfreeze(now int64) ([]int, error) {
+	db := sdm.db
+	query := `SELECT id FROM {{.TablePrefix}}contests WHERE (unfrozen = 0) AND (unfreeze_timestamp > 0)
+        AND (unfreeze_timestamp <= ?)`
+	stmt, err := db.Prepare(query)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+	rows, err := stmt.Query(now)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err = rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// Replace public scores of contest with private ones and mark contest as unfrozen
+func (sdm *ScoreDbModel) UnfreezeContestScores(contestId int) error {
+	// Viewers never see half copied public scores, scheduler retries from untouched ones
+	tx, err := sdm.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	queryDelete := `DELETE FROM {{.TablePrefix}}scores_public WHERE id_contest = ?`
+	stmtDelete, err := tx.Prepare(queryDelete)
+	if err != nil {
+		return err
+	}
+	defer stmtDelete.Close()
+	if _, err = stmtDelete.Exec(contestId); err != nil {
+		return err
+	}
+	queryCopy := `INSERT INTO {{.TablePrefix}}scores_public
+        (id_contest, id_problem, id_user, score, accepted_time, penalty_time, submission_count, one_hit, regraded)
+        SELECT id_contest, id_problem, id_user, score, accepted_time, penalty_time, submission_count, one_hit, regraded
+        FROM {{.TablePrefix}}scores_private WHERE id_contest = ?`
+	stmtCopy, err := tx.Prepare(queryCopy)
+	if err != nil {
+		return err
+	}
+	defer stmtCopy.Close()
+	if _, err = stmtCopy.Exec(contestId); err != nil {
+		return err
+	}
+	// Configured unfreeze time kept, actual time recorded by audit log
+	query := `UPDATE {{.TablePrefix}}contests SET unfrozen = 1 WHERE id = ?`
+	stmt, err := tx.Prepare(query)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	if _, err = stmt.Exec(contestId); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,256 @@
+package main
+
+/* GargoyleJudge - Simple Judgement System for Competitive Programming
+ * Copyright (C) Thiekus 2019
+ * Visit www.khayalan.id for updates
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/. */
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/thiekus/gargoyle-judge/internal/gytypes"
+)
+
+const (
+	ScoreExportJson       = "json"
+	ScoreExportCsv        = "csv"
+	ScoreExportIcpcTsv    = "results.tsv"
+	ScoreExportIcpcScores = "scoreboard.json"
+)
+
+type ScoreExportProblemData struct {
+	ProblemId       int    `json:"problemId"`
+	ShortName       string `json:"shortName"`
+	Name            string `json:"name"`
+	FirstSolverId   int    `json:"firstSolverId,omitempty"`
+	FirstSolverName string `json:"firstSolverName,omitempty"`
+}
+
+type ScoreExportCellData struct {
+	ProblemId       int    `json:"problemId"`
+	Score           int    `json:"score"`
+	Solved          bool   `json:"solved"`
+	AcceptedTime    int64  `json:"acceptedTime"` // Elapsed seconds since contest start
+	PenaltyTime     int64  `json:"penaltyTime"`
+	SubmissionCount int    `json:"submissionCount"`
+	FirstToSolve    bool   `json:"firstToSolve"`
+	Text            string `json:"text"`
+}
+
+type ScoreExportContestantData struct {
+	Rank        int                   `json:"rank"`
+	UserId      int                   `json:"userId"`
+	Name        string                `json:"name"`
+	Institution string                `json:"institution"`
+	CountryCode string                `json:"countryCode"`
+	Score       int                   `json:"score"`
+	Solved      int                   `json:"solved"`
+	PenaltyTime int64                 `json:"penaltyTime"`
+	Cells       []ScoreExportCellData `json:"cells"`
+}
+
+type ScoreExportData struct {
+	ContestId   int                         `json:"contestId"`
+	ContestName string                      `json:"contestName"`
+	Style       string                      `json:"style"`
+	Public      bool                        `json:"public"`
+	ExportTime  time.Time                   `json:"exportTime"`
+	Problems    []ScoreExportProblemData    `json:"problems"`
+	Standings   []ScoreExportContestantData `json:"standings"`
+}
+
+// Scoreboard row of ICPC Contest API, time counted in minutes
+type IcpcScoreboardRow struct {
+	Rank     int                     `json:"rank"`
+	TeamId   string                  `json:"team_id"`
+	Score    IcpcScoreboardScore     `json:"score"`
+	Problems []IcpcScoreboardProblem `json:"problems"`
+}
+
+type IcpcScoreboardScore struct {
+	NumSolved int   `json:"num_solved"`
+	TotalTime int64 `json:"total_time"`
+	Score     int   `json:"score,omitempty"` // Only for score based styles
+}
+
+type IcpcScoreboardProblem struct {
+	ProblemId    string `json:"problem_id"`
+	NumJudged    int    `json:"num_judged"`
+	NumPending   int    `json:"num_pending"`
+	Solved       bool   `json:"solved"`
+	Time         int64  `json:"time,omitempty"`
+	FirstToSolve bool   `json:"first_to_solve,omitempty"`
+}
+
+type IcpcScoreboardData struct {
+	Time string              `json:"time"`
+	Rows []IcpcScoreboardRow `json:"rows"`
+}
+
+// Convert scoreboard into export file of given format, returns content type and file name
+func ExportScoreboard(sb gytypes.ScoreboardData, public bool, format string) ([]byte, string, string, error) {
+	switch format {
+	case ScoreExportJson:
+		data, err := json.MarshalIndent(makeScoreExportData(sb, public), "", "  ")
+		return data, "application/json; charset=utf-8", fmt.Sprintf("scoreboard-%d.json", sb.ContestId), err
+	case ScoreExportCsv:
+		data, err := makeScoreExportCsv(sb)
+		return data, "text/csv; charset=utf-8", fmt.Sprintf("scoreboard-%d.csv", sb.ContestId), err
+	case ScoreExportIcpcTsv:
+		return makeIcpcResultsTsv(sb), "text/tab-separated-values; charset=utf-8", ScoreExportIcpcTsv, nil
+	case ScoreExportIcpcScores:
+		data, err := json.MarshalIndent(MakeIcpcScoreboard(sb), "", "  ")
+		return data, "application/json; charset=utf-8", ScoreExportIcpcScores, err
+	}
+	return nil, "", "", fmt.Errorf("unknown export format %s", format)
+}
+
+func makeScoreExportData(sb gytypes.ScoreboardData, public bool) ScoreExportData {
+	export := ScoreExportData{
+		ContestId:   sb.ContestId,
+		ContestName: sb.ContestName,
+		Style:       sb.ContestStyle,
+		Public:      public,
+		ExportTime:  sb.LastUpdate,
+	}
+	for _, prob := range sb.Problems {
+		export.Problems = append(export.Problems, ScoreExportProblemData{
+			ProblemId:       prob.ProblemId,
+			ShortName:       prob.ShortName,
+			Name:            prob.Name,
+			FirstSolverId:   prob.FirstSolverId,
+			FirstSolverName: prob.FirstSolverName,
+		})
+	}
+	for _, cs := range sb.Contestant {
+		row := ScoreExportContestantData{
+			Rank:        cs.RankNumber,
+			UserId:      cs.UserId,
+			Name:        cs.Name,
+			Institution: cs.Institution,
+			CountryCode: cs.CountryCode,
+			Score:       cs.TotalScore,
+			Solved:      cs.SolvedCount,
+			PenaltyTime: cs.TotalPenaltyTime,
+		}
+		for _, score := range cs.Problems {
+			row.Cells = append(row.Cells, ScoreExportCellData{
+				ProblemId:       score.ProblemId,
+				Score:           score.Score,
+				Solved:          score.IsAccepted,
+				AcceptedTime:    score.AcceptedTime,
+				PenaltyTime:     score.PenaltyTime,
+				SubmissionCount: score.SubmissionCount,
+				FirstToSolve:    score.OneHit,
+				Text:            score.CellText,
+			})
+		}
+		export.Standings = append(export.Standings, row)
+	}
+	return export
+}
+
+// One row per contestant, each problem having score, attempts and accepted time columns
+func makeScoreExportCsv(sb gytypes.ScoreboardData) ([]byte, error) {
+	var buf bytes.Buffer
+	cw := csv.NewWriter(&buf)
+	header := []string{"Rank", "Contestant", "Institution", "Country", "Score", "Solved"}
+	if sb.ShowPenalty {
+		header = append(header, "Penalty")
+	}
+	for _, prob := range sb.Problems {
+		header = append(header, prob.ShortName, prob.ShortName+" Attempts", prob.ShortName+" Time")
+	}
+	if err := cw.Write(header); err != nil {
+		return nil, err
+	}
+	for _, cs := range sb.Contestant {
+		record := []string{
+			strconv.Itoa(cs.RankNumber),
+			cs.Name,
+			cs.Institution,
+			cs.CountryCode,
+			strconv.Itoa(cs.TotalScore),
+			strconv.Itoa(cs.SolvedCount),
+		}
+		if sb.ShowPenalty {
+			record = append(record, cs.PenaltyTimeStr)
+		}
+		for _, score := range cs.Problems {
+			acceptedTime := ""
+			if score.AcceptedTime > 0 {
+				acceptedTime = score.AcceptedTimeStr
+			}
+			record = append(record, strconv.Itoa(score.Score), strconv.Itoa(score.SubmissionCount), acceptedTime)
+		}
+		if err := cw.Write(record); err != nil {
+			return nil, err
+		}
+	}
+	cw.Flush()
+	return buf.Bytes(), cw.Error()
+}
+
+// ICPC results.tsv, contestants without solved problem listed as honorable without rank
+func makeIcpcResultsTsv(sb gytypes.ScoreboardData) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("results\t1\n")
+	for _, cs := range sb.Contestant {
+		rank := ""
+		award := "Honorable"
+		if cs.SolvedCount > 0 {
+			rank = strconv.Itoa(cs.RankNumber)
+			award = "Ranked"
+		}
+		fmt.Fprintf(&buf, "%d\t%s\t%s\t%d\t%d\t%d\n", cs.UserId, rank, award, cs.SolvedCount,
+			cs.TotalPenaltyTime/60, cs.LastScoreTime/60)
+	}
+	return buf.Bytes()
+}
+
+// Scoreboard in ICPC Contest API format, team and problem ids are user id and problem short name
+func MakeIcpcScoreboard(sb gytypes.ScoreboardData) IcpcScoreboardData {
+	icpc := IcpcScoreboardData{
+		Time: sb.LastUpdate.Format("2006-01-02T15:04:05.000Z07:00"),
+		Rows: []IcpcScoreboardRow{},
+	}
+	for _, cs := range sb.Contestant {
+		row := IcpcScoreboardRow{
+			Rank:   cs.RankNumber,
+			TeamId: strconv.Itoa(cs.UserId),
+			Score: IcpcScoreboardScore{
+				NumSolved: cs.SolvedCount,
+				TotalTime: cs.TotalPenaltyTime / 60,
+			},
+			Problems: []IcpcScoreboardProblem{},
+		}
+		if sb.ContestStyle != gytypes.ScoreStyleICPC {
+			row.Score.Score = cs.TotalScore
+		}
+		for i, score := range cs.Problems {
+			if score.SubmissionCount == 0 {
+				continue
+			}
+			prob := IcpcScoreboardProblem{
+				ProblemId:    sb.Problems[i].ShortName,
+				NumJudged:    score.SubmissionCount,
+				Solved:       score.IsAccepted,
+				FirstToSolve: score.OneHit,
+			}
+			if score.IsAccepted {
+				prob.Time = score.AcceptedTime / 60
+			}
+			row.Problems = append(row.Problems, prob)
+		}
+		icpc.Rows = append(icpc.Rows, row)
+	}
+	return icpc
+}
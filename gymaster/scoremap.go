@@ -10,6 +10,8 @@ package main
 
 import (
 	"errors"
+	"fmt"
+	"strconv"
 	"sync"
 	"time"
 
@@ -27,8 +29,9 @@ type ScoreboardMap struct {
 }
 
 type ScoreboardController struct {
-	smapPublic  ScoreboardMap
-	smapPrivate ScoreboardMap
+	smapPublic   ScoreboardMap
+	smapPrivate  ScoreboardMap
+	unfreezeQuit chan bool
 }
 
 func MakeScoreboardController() ScoreboardController {
@@ -185,6 +188,8 @@ func (sbc *ScoreboardController) InvalidateScoreboardCache(contestId int) {
 		sdc.NeedRefresh = true
 		sbc.storePublicScoreboardMap(contestId, sdc)
 	}
+	// Watching clients get changed rows after recomputed
+	appLiveUpdates.ScoreboardChanged(contestId)
 }
 
 func (sbc *ScoreboardController) RefreshPublicScoreboard(contestId int) error {
@@ -246,7 +251,8 @@ func (sbc *ScoreboardController) GetColorWheel(arrayLen int) []string {
 	return c
 }
 
-func (sbc *ScoreboardController) SubmitScore(problemId, userId, score int, accepted bool) error {
+// Update score of user on a problem after its submission judged
+func (sbc *ScoreboardController) UpdateUserScore(problemId, userId int) error {
 	db, err := OpenDatabase()
 	if err != nil {
 		return err
@@ -257,11 +263,69 @@ func (sbc *ScoreboardController) SubmitScore(problemId, userId, score int, accep
 	if err != nil {
 		return err
 	}
-	err = sdm.SubmitIntoScoreboard(problemId, userId, score, accepted)
+	firstSolvers, err := sdm.RecomputeProblemScore(problemId, userId)
 	if err != nil {
 		return err
 	}
 	// Now invalidate caches
 	sbc.InvalidateScoreboardCache(ci.ContestId)
+	if len(firstSolvers) > 0 {
+		return sbc.notifyFirstSolvers(db, ci, problemId, firstSolvers)
+	}
+	return nil
+}
+
+// Tell all jury members that a problem just solved first time on contest,
+// first solve within group is never told as not stored and may move later
+func (sbc *ScoreboardController) notifyFirstSolvers(db DbContext, ci gytypes.ScoreContestInfo, problemId int,
+	firstSolvers []int) error {
+	cdm := NewContestDbModel(db)
+	prob, err := cdm.GetProblemById(problemId)
+	if err != nil {
+		return err
+	}
+	udm := NewUserDbModel(db)
+	users, err := udm.GetUserList()
+	if err != nil {
+		return err
+	}
+	userNames := make(map[int]string)
+	for _, user := range users {
+		userNames[user.Id] = user.Username
+	}
+	link := "/dashboard/scoreboard/" + strconv.Itoa(ci.ContestId)
+	for _, solverId := range firstSolvers {
+		desc := fmt.Sprintf("%s is first to solve problem %s - %s on %s", userNames[solverId], prob.ShortName,
+			prob.Name, ci.Title)
+		for _, user := range users {
+			if !user.Roles.Jury || !user.Active {
+				continue
+			}
+			if err = appNotifications.AddNotification(user.Id, solverId, desc, link); err != nil {
+				return err
+			}
+		}
+	}
 	return nil
 }
+
+// Recompute all scores of contest from submissions history, differences only written when apply is set
+func (sbc *ScoreboardController) RebuildContestScores(contestId int, apply bool) (gytypes.ScoreRebuildPlan, error) {
+	db, err := OpenDatabase()
+	if err != nil {
+		return gytypes.ScoreRebuildPlan{}, err
+	}
+	defer db.Close()
+	sdm := NewScoreDbModel(db)
+	plan, err := sdm.PlanContestRebuild(contestId)
+	if err != nil {
+		return plan, err
+	}
+	if !apply || (len(plan.Diffs) == 0) {
+		return plan, nil
+	}
+	err = sdm.ApplyContestRebuild(plan)
+	// Some of changes may already written even on error
+	sbc.InvalidateScoreboardCache(contestId)
+	return plan, err
+}
@@ -0,0 +1,176 @@
+package main
+
+/* GargoyleJudge - Simple Judgement System for Competitive Programming
+ * Copyright (C) Thiekus 2019
+ * Visit www.khayalan.id for updates
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/. */
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+
+	"github.com/thiekus/gargoyle-judge/internal/gytypes"
+)
+
+type ResolverStatusData struct {
+	ContestId   int
+	ContestName string
+	Pending     int // Frozen score cells not yet revealed
+}
+
+// Result of revealing one frozen score cell
+type ResolverRevealStep struct {
+	UserName    string
+	ProblemName string
+	RankBefore  int
+	RankAfter   int
+	Solved      bool
+	Remaining   int
+}
+
+// Get frozen score cells of contestants on public scoreboard, which differ from private one
+func (sbc *ScoreboardController) getPendingScores(sdm *ScoreDbModel, sb *gytypes.ScoreboardData) (map[scoreCellKey]bool, error) {
+	privateScores, err := sdm.GetScoresOfContest(sb.ContestId, false)
+	if err != nil {
+		return nil, err
+	}
+	publicScores, err := sdm.GetScoresOfContest(sb.ContestId, true)
+	if err != nil {
+		return nil, err
+	}
+	contestants := make(map[int]bool)
+	for _, cs := range sb.Contestant {
+		contestants[cs.UserId] = true
+	}
+	publicMap := make(map[scoreCellKey]gytypes.ScoreProblemData)
+	for _, score := range publicScores {
+		publicMap[scoreCellKey{score.UserId, score.ProblemId}] = score
+	}
+	pending := make(map[scoreCellKey]bool)
+	for _, score := range privateScores {
+		key := scoreCellKey{score.UserId, score.ProblemId}
+		public, exists := publicMap[key]
+		if contestants[key.userId] && (!exists || !isSameProblemScore(score, public)) {
+			pending[key] = true
+		}
+	}
+	return pending, nil
+}
+
+// Get public scoreboard of contest with frozen scores, error if contest never freezed
+func (sbc *ScoreboardController) getFrozenScoreboard(sdm *ScoreDbModel, contestId int) (*gytypes.ScoreboardData, error) {
+	sci, err := sdm.GetContestInfoById(contestId)
+	if err != nil {
+		return nil, err
+	}
+	if sdm.getScoreCutoff(sci, true) <= 0 {
+		return nil, errors.New("contest has no freeze time or already unfrozen")
+	}
+	return sdm.GetScoreboardForContest(contestId, true)
+}
+
+func (sbc *ScoreboardController) GetResolverStatus(contestId int) (ResolverStatusData, error) {
+	status := ResolverStatusData{ContestId: contestId}
+	db, err := OpenDatabase()
+	if err != nil {
+		return status, err
+	}
+	defer db.Close()
+	sdm := NewScoreDbModel(db)
+	sb, err := sbc.getFrozenScoreboard(&sdm, contestId)
+	if err != nil {
+		return status, err
+	}
+	status.ContestName = sb.ContestName
+	pending, err := sbc.getPendingScores(&sdm, sb)
+	if err != nil {
+		return status, err
+	}
+	status.Pending = len(pending)
+	return status, nil
+}
+
+// Export contest as ICPC Contest API event feed, as read by resolver tools. Scoreboard must
+// still be frozen, since resolver replays judgements made during freeze itself
+func (sbc *ScoreboardController) GetResolverExport(contestId int) ([]byte, error) {
+	db, err := OpenDatabase()
+	if err != nil {
+		return nil, err
+	}
+	sdm := NewScoreDbModel(db)
+	_, err = sbc.getFrozenScoreboard(&sdm, contestId)
+	db.Close()
+	if err != nil {
+		return nil, err
+	}
+	snap, err := GetContestApiSnapshot(contestId)
+	if err != nil {
+		return nil, err
+	}
+	state := MakeContestApiFeedState()
+	events, err := state.diffSnapshot(snap)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	for _, event := range events {
+		if err = encoder.Encode(event); err != nil {
+			return nil, err
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// Reveal next frozen score cell on public scoreboard, from bottom-ranked contestant upward
+func (sbc *ScoreboardController) RevealNextScore(contestId, revealedBy int) (ResolverRevealStep, error) {
+	step := ResolverRevealStep{}
+	db, err := OpenDatabase()
+	if err != nil {
+		return step, err
+	}
+	defer db.Close()
+	sdm := NewScoreDbModel(db)
+	before, err := sbc.getFrozenScoreboard(&sdm, contestId)
+	if err != nil {
+		return step, err
+	}
+	pending, err := sbc.getPendingScores(&sdm, before)
+	if err != nil {
+		return step, err
+	}
+	for i := len(before.Contestant) - 1; i >= 0; i-- {
+		cs := before.Contestant[i]
+		for j, score := range cs.Problems {
+			key := scoreCellKey{cs.UserId, score.ProblemId}
+			if !pending[key] {
+				continue
+			}
+			if err = sdm.RevealScore(contestId, score.ProblemId, cs.UserId, revealedBy); err != nil {
+				return step, err
+			}
+			sbc.InvalidateScoreboardCache(contestId)
+			step.UserName = cs.Name
+			step.ProblemName = before.Problems[j].ShortName
+			step.RankBefore = cs.RankNumber
+			step.Remaining = len(pending) - 1
+			after, err := sdm.GetScoreboardForContest(contestId, true)
+			if err != nil {
+				return step, err
+			}
+			for _, csAfter := range after.Contestant {
+				if csAfter.UserId == cs.UserId {
+					step.RankAfter = csAfter.RankNumber
+					step.Solved = csAfter.Problems[j].IsAccepted
+					break
+				}
+			}
+			return step, nil
+		}
+	}
+	return step, errors.New("no frozen score left to reveal")
+}
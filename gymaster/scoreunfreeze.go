@@ -0,0 +1,113 @@
+package main
+
+/* GargoyleJudge - Simple Judgement System for Competitive Programming
+ * Copyright (C) Thiekus 2019
+ * Visit www.khayalan.id for updates
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/. */
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/thiekus/gargoyle-judge/internal/gylib"
+)
+
+// Interval of checking contests reached their unfreeze time
+const scoreUnfreezeInterval = 30 * time.Second
+
+// Start of unfreeze audit log details, followed by contest title
+func getUnfreezeAuditPrefix(contestId int) string {
+	return fmt.Sprintf("Scoreboard of contest %d (", contestId)
+}
+
+func (sbc *ScoreboardController) StartUnfreezeScheduler() {
+	if sbc.unfreezeQuit != nil {
+		return
+	}
+	quit := make(chan bool)
+	sbc.unfreezeQuit = quit
+	go func() {
+		log := gylib.GetStdLog()
+		ticker := time.NewTicker(scoreUnfreezeInterval)
+		defer ticker.Stop()
+		for {
+			if err := sbc.unfreezeDueContests(); err != nil {
+				log.Errorf("Scoreboard unfreeze error: %s", err.Error())
+			}
+			select {
+			case <-quit:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+func (sbc *ScoreboardController) StopUnfreezeScheduler() {
+	if sbc.unfreezeQuit == nil {
+		return
+	}
+	close(sbc.unfreezeQuit)
+	sbc.unfreezeQuit = nil
+}
+
+func (sbc *ScoreboardController) unfreezeDueContests() error {
+	db, err := OpenDatabase()
+	if err != nil {
+		return err
+	}
+	sdm := NewScoreDbModel(db)
+	ids, err := sdm.GetContestsToUnfreeze(time.Now().Unix())
+	db.Close()
+	if err != nil {
+		return err
+	}
+	// One broken contest never keeps others frozen
+	log := gylib.GetStdLog()
+	var failed []string
+	for _, id := range ids {
+		if err = sbc.UnfreezeContest(id, 0); err != nil {
+			log.Errorf("Cannot unfreeze scoreboard of contest %d: %s", id, err.Error())
+			failed = append(failed, fmt.Sprintf("contest %d: %s", id, err.Error()))
+		}
+	}
+	if len(failed) > 0 {
+		return errors.New(strings.Join(failed, "; "))
+	}
+	return nil
+}
+
+// Sync public scoreboard with private one, userId is 0 if done by scheduler
+func (sbc *ScoreboardController) UnfreezeContest(contestId, userId int) error {
+	db, err := OpenDatabase()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	sdm := NewScoreDbModel(db)
+	sci, err := sdm.GetContestInfoById(contestId)
+	if err != nil {
+		return err
+	}
+	if sci.Unfrozen {
+		return errors.New("scoreboard already unfrozen")
+	}
+	if err = sdm.UnfreezeContestScores(contestId); err != nil {
+		return err
+	}
+	sbc.InvalidateScoreboardCache(contestId)
+	by := "automatically"
+	if userId > 0 {
+		by = fmt.Sprintf("by uid:%d", userId)
+	}
+	details := fmt.Sprintf("%s%s) unfrozen %s", getUnfreezeAuditPrefix(contestId), sci.Title, by)
+	log := gylib.GetStdLog()
+	log.Print(details)
+	adm := NewAuditDbModel(db)
+	return adm.InsertAuditLog(userId, AuditScoreboardUnfreeze, details)
+}
@@ -0,0 +1,198 @@
+package main
+
+/* GargoyleJudge - Simple Judgement System for Competitive Programming
+ * Copyright (C) Thiekus 2019
+ * Visit www.khayalan.id for updates
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/. */
+
+import (
+	"crypto/subtle"
+	"database/sql"
+	"encoding/json"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/thiekus/gargoyle-judge/internal/gylib"
+	"github.com/thiekus/gargoyle-judge/internal/gyrpc"
+	"github.com/thiekus/gargoyle-judge/internal/gytypes"
+)
+
+// Read registration request and validate its token, returns nil if response already written
+func readSlaveApiRequest(w http.ResponseWriter, r *http.Request) *gyrpc.SlaveRegisterRequest {
+	if appConfig.SlaveToken == "" {
+		http.Error(w, "403 Forbidden: slave registration disabled", http.StatusForbidden)
+		return nil
+	}
+	var req gyrpc.SlaveRegisterRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "400 Bad Request: "+err.Error(), http.StatusBadRequest)
+		return nil
+	}
+	if subtle.ConstantTimeCompare([]byte(req.Token), []byte(appConfig.SlaveToken)) != 1 {
+		log := gylib.GetStdLog()
+		log.Errorf("Slave registration from %s rejected: invalid token", r.RemoteAddr)
+		http.Error(w, "403 Forbidden: invalid token", http.StatusForbidden)
+		return nil
+	}
+	// Slave may only know its port, take host as seen by master
+	host, port, err := net.SplitHostPort(req.Address)
+	if err != nil {
+		http.Error(w, "400 Bad Request: "+err.Error(), http.StatusBadRequest)
+		return nil
+	}
+	if host == "" {
+		if host, _, err = net.SplitHostPort(r.RemoteAddr); err != nil {
+			http.Error(w, "400 Bad Request: "+err.Error(), http.StatusBadRequest)
+			return nil
+		}
+		req.Address = net.JoinHostPort(host, port)
+	}
+	if req.Address == embeddedSlaveAddress {
+		http.Error(w, "400 Bad Request: reserved address", http.StatusBadRequest)
+		return nil
+	}
+	if req.Name == "" {
+		req.Name = req.Address
+	}
+	return &req
+}
+
+func writeSlaveApiResponse(w http.ResponseWriter, slave gytypes.SlaveData) {
+	resp := gyrpc.SlaveRegisterResponse{
+		Id:       slave.Id,
+		Address:  slave.Address,
+		Approved: slave.Approved,
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	if data, err := json.Marshal(resp); err == nil {
+		w.Write(data)
+	} else {
+		http.Error(w, "500 Internal Server Error: "+err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// Refresh slaves without blocking slave api request
+func refreshSlavesLater() {
+	go func() {
+		if err := appSlaves.RefreshSlaves(); err != nil {
+			log := gylib.GetStdLog()
+			log.Errorf("Cannot refresh slaves: %s", err.Error())
+		}
+	}()
+}
+
+func slaveApiRegisterPostEndpoint(w http.ResponseWriter, r *http.Request) {
+	req := readSlaveApiRequest(w, r)
+	if req == nil {
+		return
+	}
+	log := gylib.GetStdLog()
+	db, err := OpenDatabase()
+	if err != nil {
+		http.Error(w, "500 Internal Server Error: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+	sdm := NewSlaveDbModel(db)
+	now := time.Now().Unix()
+	slave, err := sdm.GetSlaveByAddress(req.Address)
+	if err == sql.ErrNoRows {
+		// Unknown slave, wait for admin approval
+		slave = gytypes.SlaveData{
+			Name:      req.Name,
+			Address:   req.Address,
+			Enable:    true,
+			Weight:    1,
+			Capacity:  2,
+			Approved:  false,
+			Heartbeat: now,
+		}
+		if err = sdm.InsertSlave(slave); err == nil {
+			slave, err = sdm.GetSlaveByAddress(req.Address)
+		}
+		if err == nil {
+			log.Warnf("New slave %s (%s) registered, waiting for approval", slave.Name, slave.Address)
+		}
+	} else if err == nil {
+		slave.Heartbeat = now
+		err = sdm.SetSlaveHeartbeat(slave.Id, now)
+		log.Printf("Slave %s (%s) registered", slave.Name, slave.Address)
+	}
+	if err != nil {
+		http.Error(w, "500 Internal Server Error: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if slave.Approved && slave.Enable {
+		refreshSlavesLater()
+	}
+	writeSlaveApiResponse(w, slave)
+}
+
+func slaveApiHeartbeatPostEndpoint(w http.ResponseWriter, r *http.Request) {
+	req := readSlaveApiRequest(w, r)
+	if req == nil {
+		return
+	}
+	db, err := OpenDatabase()
+	if err != nil {
+		http.Error(w, "500 Internal Server Error: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+	sdm := NewSlaveDbModel(db)
+	slave, err := sdm.GetSlaveByAddress(req.Address)
+	if err == sql.ErrNoRows {
+		// Probably removed by admin, let slave register again
+		http.Error(w, "404 Not Found: slave not registered", http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, "500 Internal Server Error: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	slave.Heartbeat = time.Now().Unix()
+	if err = sdm.SetSlaveHeartbeat(slave.Id, slave.Heartbeat); err != nil {
+		http.Error(w, "500 Internal Server Error: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	// Bring it back soon instead of waiting next health check
+	if slave.Approved && slave.Enable && !appSlaves.IsSlaveActive(slave.Address) {
+		refreshSlavesLater()
+	}
+	writeSlaveApiResponse(w, slave)
+}
+
+func slaveApiDeregisterPostEndpoint(w http.ResponseWriter, r *http.Request) {
+	req := readSlaveApiRequest(w, r)
+	if req == nil {
+		return
+	}
+	log := gylib.GetStdLog()
+	db, err := OpenDatabase()
+	if err != nil {
+		http.Error(w, "500 Internal Server Error: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer db.Close()
+	sdm := NewSlaveDbModel(db)
+	slave, err := sdm.GetSlaveByAddress(req.Address)
+	if err == sql.ErrNoRows {
+		http.Error(w, "404 Not Found: slave not registered", http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, "500 Internal Server Error: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	slave.Heartbeat = 0
+	if err = sdm.SetSlaveHeartbeat(slave.Id, 0); err != nil {
+		http.Error(w, "500 Internal Server Error: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	// Stop scheduling new jobs into it right away
+	appSlaves.MarkSlaveDown(slave)
+	log.Printf("Slave %s (%s) deregistered", slave.Name, slave.Address)
+	writeSlaveApiResponse(w, slave)
+}
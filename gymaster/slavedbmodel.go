@@ -25,7 +25,7 @@ func NewSlaveDbModel(db DbContext) SlaveDbModel {
 
 func (sdm *SlaveDbModel) GetSlaveList() ([]gytypes.SlaveData, error) {
 	db := sdm.db
-	query := `SELECT id, name, address, enable FROM {{.TablePrefix}}slaves`
+	query := `SELECT id, name, address, enable, weight, capacity, approved, heartbeat_time FROM {{.TablePrefix}}slaves`
 	rows, err := db.Query(query)
 	if err != nil {
 		return nil, err
@@ -38,6 +38,10 @@ func (sdm *SlaveDbModel) GetSlaveList() ([]gytypes.SlaveData, error) {
 			&slave.Name,
 			&slave.Address,
 			&slave.Enable,
+			&slave.Weight,
+			&slave.Capacity,
+			&slave.Approved,
+			&slave.Heartbeat,
 		)
 		if err != nil {
 			return nil, err
@@ -46,3 +50,117 @@ func (sdm *SlaveDbModel) GetSlaveList() ([]gytypes.SlaveData, error) {
 	}
 	return slist, nil
 }
+
+func (sdm *SlaveDbModel) GetSlaveById(id int) (gytypes.SlaveData, error) {
+	return sdm.getSlaveBy("id", id)
+}
+
+func (sdm *SlaveDbModel) GetSlaveByAddress(address string) (gytypes.SlaveData, error) {
+	return sdm.getSlaveBy("address", address)
+}
+
+func (sdm *SlaveDbModel) getSlaveBy(field string, value interface{}) (gytypes.SlaveData, error) {
+	slave := gytypes.SlaveData{}
+	db := sdm.db
+	query := `SELECT id, name, address, enable, weight, capacity, approved, heartbeat_time FROM {{.TablePrefix}}slaves
+        WHERE ` + field + ` = ?`
+	stmt, err := db.Prepare(query)
+	if err != nil {
+		return slave, err
+	}
+	defer stmt.Close()
+	err = stmt.QueryRow(value).Scan(
+		&slave.Id,
+		&slave.Name,
+		&slave.Address,
+		&slave.Enable,
+		&slave.Weight,
+		&slave.Capacity,
+		&slave.Approved,
+		&slave.Heartbeat,
+	)
+	return slave, err
+}
+
+func (sdm *SlaveDbModel) InsertSlave(slave gytypes.SlaveData) error {
+	db := sdm.db
+	query := `INSERT INTO {{.TablePrefix}}slaves (name, address, enable, weight, capacity, approved, heartbeat_time)
+        VALUES (?, ?, ?, ?, ?, ?, ?)`
+	stmt, err := db.Prepare(query)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	_, err = stmt.Exec(
+		slave.Name,
+		slave.Address,
+		slave.Enable,
+		slave.Weight,
+		slave.Capacity,
+		slave.Approved,
+		slave.Heartbeat,
+	)
+	return err
+}
+
+func (sdm *SlaveDbModel) ModifySlave(id int, slave gytypes.SlaveData) error {
+	db := sdm.db
+	query := `UPDATE {{.TablePrefix}}slaves SET
+        name = ?,
+        address = ?,
+        enable = ?,
+        weight = ?,
+        capacity = ?
+        WHERE id = ?`
+	stmt, err := db.Prepare(query)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	_, err = stmt.Exec(
+		slave.Name,
+		slave.Address,
+		slave.Enable,
+		slave.Weight,
+		slave.Capacity,
+		id,
+	)
+	return err
+}
+
+func (sdm *SlaveDbModel) DeleteSlaveById(id int) error {
+	db := sdm.db
+	query := `DELETE FROM {{.TablePrefix}}slaves WHERE id = ?`
+	stmt, err := db.Prepare(query)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	_, err = stmt.Exec(id)
+	return err
+}
+
+func (sdm *SlaveDbModel) SetSlaveApproved(id int, approved bool) error {
+	db := sdm.db
+	query := `UPDATE {{.TablePrefix}}slaves SET approved = ? WHERE id = ?`
+	stmt, err := db.Prepare(query)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	_, err = stmt.Exec(approved, id)
+	return err
+}
+
+// Zero heartbeat time means slave has been deregistered
+func (sdm *SlaveDbModel) SetSlaveHeartbeat(id int, heartbeat int64) error {
+	db := sdm.db
+	query := `UPDATE {{.TablePrefix}}slaves SET heartbeat_time = ? WHERE id = ?`
+	stmt, err := db.Prepare(query)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	_, err = stmt.Exec(heartbeat, id)
+	return err
+}
@@ -13,17 +13,26 @@ import (
 	"sync"
 	"time"
 
+	"github.com/thiekus/gargoyle-judge/internal/gylib"
 	"github.com/thiekus/gargoyle-judge/internal/gyrpc"
+	"github.com/thiekus/gargoyle-judge/internal/gytask"
 	"github.com/thiekus/gargoyle-judge/internal/gytypes"
 )
 
+const embeddedSlaveAddress = "embedded"
+
+// Interval of background slave health checking
+const slaveHealthInterval = 15 * time.Second
+
 type SlaveManager struct {
-	slaveCount   int
-	activeCount  int
-	slaves       []gytypes.SlaveData
-	selIndex     int
-	refreshMutex sync.Mutex
-	fetchMutex   sync.Mutex
+	slaveCount     int
+	activeCount    int
+	slaves         []gytypes.SlaveData
+	inFlight       map[string]int // Running jobs keyed by slave address
+	refreshMutex   sync.Mutex
+	fetchMutex     sync.Mutex
+	embeddedServer *gyrpc.GargoyleRpcServer
+	healthQuit     chan bool
 }
 
 func MakeSlaveManager() SlaveManager {
@@ -31,17 +40,51 @@ func MakeSlaveManager() SlaveManager {
 		slaveCount:  0,
 		activeCount: 0,
 		slaves:      []gytypes.SlaveData{},
-		selIndex:    0,
+		inFlight:    make(map[string]int),
+	}
+	if appConfig.EmbeddedSlave {
+		// Embedded slave never listening, only invoked in-process
+		server, err := gyrpc.NewGargoyleRpcServer(embeddedSlaveAddress, gytask.SlaveTaskHandler{})
+		if err != nil {
+			log := gylib.GetStdLog()
+			log.Errorf("Cannot initialize embedded slave: %s", err.Error())
+		} else {
+			sm.embeddedServer = server
+		}
 	}
 	return sm
 }
 
-func (sm *SlaveManager) CheckForRefresh() error {
-	if sm.activeCount == 0 {
-		err := sm.RefreshSlaves()
-		return err
+// Begin background health checking, so pinging never done on submission path
+func (sm *SlaveManager) StartHealthCheck() {
+	if sm.healthQuit != nil {
+		return
 	}
-	return nil
+	quit := make(chan bool)
+	sm.healthQuit = quit
+	go func() {
+		log := gylib.GetStdLog()
+		ticker := time.NewTicker(slaveHealthInterval)
+		defer ticker.Stop()
+		for {
+			if err := sm.RefreshSlaves(); err != nil {
+				log.Errorf("Slave health check error: %s", err.Error())
+			}
+			select {
+			case <-quit:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+func (sm *SlaveManager) StopHealthCheck() {
+	if sm.healthQuit == nil {
+		return
+	}
+	close(sm.healthQuit)
+	sm.healthQuit = nil
 }
 
 func (sm *SlaveManager) RefreshSlaves() error {
@@ -57,28 +100,77 @@ func (sm *SlaveManager) RefreshSlaves() error {
 	if err != nil {
 		return err
 	}
+	if sm.embeddedServer != nil {
+		embedded := gytypes.SlaveData{
+			Id:       0,
+			Name:     "Embedded Slave",
+			Address:  embeddedSlaveAddress,
+			Enable:   true,
+			Embedded: true,
+			Weight:   1,
+			Capacity: 1,
+			Approved: true,
+		}
+		sl = append(sl, embedded)
+	}
 	activeCount := 0
 	for idx, slave := range sl {
-		_, err := sm.TestPing(slave.Address)
+		// Unapproved slave never used, even if reachable
+		if !slave.Enable || !slave.Approved {
+			continue
+		}
+		resp, err := sm.pingSlave(slave)
 		sl[idx].Active = err == nil
 		if err == nil {
+			sl[idx].Latency = float64(resp.Delta) / float64(time.Millisecond)
+			sl[idx].OSName = resp.OSName
+			sl[idx].Arch = resp.Arch
+			sl[idx].NumCPU = resp.NumCPU
 			activeCount++
 		}
 	}
+	sm.fetchMutex.Lock()
+	defer sm.fetchMutex.Unlock()
+	for idx, slave := range sl {
+		sl[idx].InFlight = sm.inFlight[slave.Address]
+	}
 	sm.activeCount = activeCount
 	sm.slaveCount = len(sl)
 	sm.slaves = sl
-	sm.selIndex = 0
 	return nil
 }
 
-func (sm *SlaveManager) TestPing(address string) (float64, error) {
-	client, err := gyrpc.NewGargoyleRpcClient(address)
+// Get copy of current slave list along with its health and load
+func (sm *SlaveManager) GetSlaves() []gytypes.SlaveData {
+	sm.fetchMutex.Lock()
+	defer sm.fetchMutex.Unlock()
+	sl := make([]gytypes.SlaveData, len(sm.slaves))
+	copy(sl, sm.slaves)
+	return sl
+}
+
+// Open connection to slave, either remote RPC or embedded one
+func (sm *SlaveManager) OpenSlaveClient(slave gytypes.SlaveData) (gyrpc.GargoyleClient, error) {
+	if slave.Embedded {
+		if sm.embeddedServer == nil {
+			return nil, errors.New("embedded slave is not available")
+		}
+		return gyrpc.NewGargoyleLocalClient(sm.embeddedServer), nil
+	}
+	return gyrpc.NewGargoyleRpcClient(slave.Address)
+}
+
+func (sm *SlaveManager) pingSlave(slave gytypes.SlaveData) (gyrpc.RpcPingResponse, error) {
+	client, err := sm.OpenSlaveClient(slave)
 	if err != nil {
-		return 0, err
+		return gyrpc.RpcPingResponse{}, err
 	}
 	defer client.Close()
-	resp, err := client.PingSlave()
+	return client.PingSlave()
+}
+
+func (sm *SlaveManager) TestPing(slave gytypes.SlaveData) (float64, error) {
+	resp, err := sm.pingSlave(slave)
 	if err != nil {
 		return 0, err
 	}
@@ -86,34 +178,71 @@ func (sm *SlaveManager) TestPing(address string) (float64, error) {
 	return delta, nil
 }
 
-func (sm *SlaveManager) GetActiveSlave() (*gytypes.SlaveData, error) {
-	if err := sm.CheckForRefresh(); err != nil {
-		return nil, err
-	}
+// Reserve least loaded healthy slave for a job, must be released by ReleaseSlave.
+// Excluded slave addresses only picked when nothing else available.
+func (sm *SlaveManager) AcquireSlave(excluded map[string]bool) (*gytypes.SlaveData, error) {
 	sm.fetchMutex.Lock()
 	defer sm.fetchMutex.Unlock()
-	idx := sm.selIndex
-	originIdx := idx
-	var selSlave *gytypes.SlaveData
-	for {
-		sl := sm.slaves[idx]
-		if _, err := sm.TestPing(sl.Address); err == nil {
-			// set for next slave role
-			sm.selIndex = idx + 1
-			if sm.selIndex >= sm.slaveCount {
-				sm.selIndex = 0
+	selIdx := -1
+	fallbackIdx := -1
+	for idx, sl := range sm.slaves {
+		if !sl.Enable || !sl.Approved || !sl.Active || !sl.HasCapacity() {
+			continue
+		}
+		if excluded[sl.Address] {
+			if (fallbackIdx < 0) || (sl.GetLoad() < sm.slaves[fallbackIdx].GetLoad()) {
+				fallbackIdx = idx
 			}
-			// use selected slave
-			selSlave = &sl
-			break
+			continue
+		}
+		if (selIdx < 0) || (sl.GetLoad() < sm.slaves[selIdx].GetLoad()) {
+			selIdx = idx
+		}
+	}
+	if selIdx < 0 {
+		selIdx = fallbackIdx
+	}
+	if selIdx < 0 {
+		return nil, errors.New("no available active slave to process")
+	}
+	sm.slaves[selIdx].InFlight++
+	sm.inFlight[sm.slaves[selIdx].Address]++
+	selSlave := sm.slaves[selIdx]
+	return &selSlave, nil
+}
+
+func (sm *SlaveManager) ReleaseSlave(slave gytypes.SlaveData) {
+	sm.fetchMutex.Lock()
+	defer sm.fetchMutex.Unlock()
+	if sm.inFlight[slave.Address] > 0 {
+		sm.inFlight[slave.Address]--
+	}
+	for idx, sl := range sm.slaves {
+		if sl.Address == slave.Address {
+			sm.slaves[idx].InFlight = sm.inFlight[slave.Address]
 		}
-		idx++
-		if idx >= sm.slaveCount {
-			idx = 0
+	}
+}
+
+// Take slave out of rotation until next health check
+func (sm *SlaveManager) MarkSlaveDown(slave gytypes.SlaveData) {
+	sm.fetchMutex.Lock()
+	defer sm.fetchMutex.Unlock()
+	for idx, sl := range sm.slaves {
+		if (sl.Address == slave.Address) && sl.Active {
+			sm.slaves[idx].Active = false
+			sm.activeCount--
 		}
-		if idx == originIdx {
-			return nil, errors.New("no available active slave to process")
+	}
+}
+
+func (sm *SlaveManager) IsSlaveActive(address string) bool {
+	sm.fetchMutex.Lock()
+	defer sm.fetchMutex.Unlock()
+	for _, sl := range sm.slaves {
+		if sl.Address == address {
+			return sl.Active
 		}
 	}
-	return selSlave, nil
+	return false
 }
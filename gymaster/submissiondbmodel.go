@@ -9,6 +9,8 @@ package main
  * file, You can obtain one at https://mozilla.org/MPL/2.0/. */
 
 import (
+	"errors"
+	"strconv"
 	"strings"
 	"time"
 
@@ -31,7 +33,7 @@ func (sdm *SubmissionDbModel) GetSubmission(subId int) (gytypes.SubmissionData,
 	si := gytypes.SubmissionData{}
 	db := sdm.db
 	query := `SELECT s.id, s.id_problem, s.id_user, s.id_lang, s.code, s.verdict, s.details, s.score, s.submit_time, s.compile_time,
-        s.compile_stdout, s.compile_stderr, u.display_name, p.problem_name, c.title
+        s.compile_stdout, s.compile_stderr, s.judge_state, s.regraded, u.display_name, p.problem_name, c.title
         FROM ((({{.TablePrefix}}submissions AS s INNER JOIN {{.TablePrefix}}users AS u ON s.id_user = u.id)
         INNER JOIN {{.TablePrefix}}problems AS p ON s.id_problem = p.id)
         INNER JOIN {{.TablePrefix}}contests AS c ON p.contest_id = c.id)
@@ -55,6 +57,8 @@ func (sdm *SubmissionDbModel) GetSubmission(subId int) (gytypes.SubmissionData,
 		&si.CompileTime,
 		&si.CompileStdout,
 		&si.CompileStderr,
+		&si.JudgeState,
+		&si.Regraded,
 		&si.UserDisplayName,
 		&si.ProblemName,
 		&si.ContestName,
@@ -74,21 +78,93 @@ func (sdm *SubmissionDbModel) GetSubmission(subId int) (gytypes.SubmissionData,
 
 func (sdm *SubmissionDbModel) GetSubmissionCount(userId int, problemId int) (int, error) {
 	db := sdm.db
-	query := `SELECT COUNT(*) FROM {{.TablePrefix}}submissions WHERE ((s.id_user = ?) OR (0 = ?)) AND ((s.id_problem = ?) OR (0 = ?))`
+	// Judging system failure and cancelled one doesn't count as attempt
+	query := `SELECT COUNT(*) FROM {{.TablePrefix}}submissions AS s WHERE ((s.id_user = ?) OR (0 = ?)) AND ((s.id_problem = ?) OR (0 = ?))
+        AND (s.verdict NOT IN (?` + strings.Repeat(", ?", len(gytypes.UncountedAttemptVerdicts)-1) + `))`
 	stmt, err := db.Prepare(query)
 	if err != nil {
 		return 0, err
 	}
 	defer stmt.Close()
+	args := []interface{}{userId, userId, problemId, problemId}
+	for _, verdict := range gytypes.UncountedAttemptVerdicts {
+		args = append(args, verdict)
+	}
 	var count int
-	err = stmt.QueryRow(userId, userId, problemId, problemId).Scan(&count)
+	err = stmt.QueryRow(args...).Scan(&count)
+	return count, err
+}
+
+// Get unix time of latest submission by user on any problem, 0 if never submitted
+func (sdm *SubmissionDbModel) GetLastSubmitTime(userId int) (int64, error) {
+	db := sdm.db
+	query := `SELECT COALESCE(MAX(submit_time), 0) FROM {{.TablePrefix}}submissions WHERE id_user = ?`
+	stmt, err := db.Prepare(query)
+	if err != nil {
+		return 0, err
+	}
+	defer stmt.Close()
+	var lastTime int64
+	err = stmt.QueryRow(userId).Scan(&lastTime)
+	return lastTime, err
+}
+
+// Get submissions of user still waiting or being judged, without its code
+func (sdm *SubmissionDbModel) GetPendingSubmissionsOfUser(userId int) ([]gytypes.SubmissionData, error) {
+	db := sdm.db
+	query := `SELECT s.id, s.id_problem, s.id_user, s.submit_time, s.judge_state, p.problem_name, c.title
+        FROM ({{.TablePrefix}}submissions AS s INNER JOIN {{.TablePrefix}}problems AS p ON s.id_problem = p.id)
+        INNER JOIN {{.TablePrefix}}contests AS c ON p.contest_id = c.id
+        WHERE (s.id_user = ?) AND (s.judge_state IN (?, ?, ?)) ORDER BY s.id ASC`
+	stmt, err := db.Prepare(query)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+	rows, err := stmt.Query(userId, gytypes.JudgeStateQueued, gytypes.JudgeStateJudging, gytypes.JudgeStateHeld)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var subs []gytypes.SubmissionData
+	for rows.Next() {
+		sb := gytypes.SubmissionData{}
+		var utSubmitTime int64
+		err = rows.Scan(
+			&sb.Id,
+			&sb.ProblemId,
+			&sb.UserId,
+			&utSubmitTime,
+			&sb.JudgeState,
+			&sb.ProblemName,
+			&sb.ContestName,
+		)
+		if err != nil {
+			return nil, err
+		}
+		sb.SubmitTime = time.Unix(utSubmitTime, 0).Local()
+		subs = append(subs, sb)
+	}
+	return subs, nil
+}
+
+func (sdm *SubmissionDbModel) GetJudgeStateCount(state string) (int, error) {
+	db := sdm.db
+	query := `SELECT COUNT(*) FROM {{.TablePrefix}}submissions WHERE judge_state = ?`
+	stmt, err := db.Prepare(query)
+	if err != nil {
+		return 0, err
+	}
+	defer stmt.Close()
+	var count int
+	err = stmt.QueryRow(state).Scan(&count)
 	return count, err
 }
 
 func (sdm *SubmissionDbModel) GetSubmissionList(userId int, problemId int) ([]gytypes.SubmissionData, error) {
 	db := sdm.db
 	query := `SELECT s.id, s.id_problem, s.id_user, s.id_lang, s.code, s.verdict, s.details, s.score, s.submit_time, s.compile_time,
-        s.compile_stdout, s.compile_stderr, u.display_name, p.problem_name, c.title
+        s.compile_stdout, s.compile_stderr, s.judge_state, s.regraded, u.display_name, p.problem_name, c.title
         FROM ((({{.TablePrefix}}submissions AS s INNER JOIN {{.TablePrefix}}users AS u ON s.id_user = u.id)
         INNER JOIN {{.TablePrefix}}problems AS p ON s.id_problem = p.id)
         INNER JOIN {{.TablePrefix}}contests AS c ON p.contest_id = c.id)
@@ -119,6 +195,8 @@ func (sdm *SubmissionDbModel) GetSubmissionList(userId int, problemId int) ([]gy
 			&sb.CompileTime,
 			&sb.CompileStdout,
 			&sb.CompileStderr,
+			&sb.JudgeState,
+			&sb.Regraded,
 			&sb.UserDisplayName,
 			&sb.ProblemName,
 			&sb.ContestName,
@@ -168,7 +246,7 @@ func (sdm *SubmissionDbModel) GetTestCasesOfProblem(problemId int) ([]gytypes.Te
 
 func (sdm *SubmissionDbModel) GetTestResultOfSubmission(submissionId int) ([]gytypes.TestResultData, error) {
 	db := sdm.db
-	query := `SELECT id, id_problem, id_submission, test_no, verdict, time_elapsed, memory_used, score FROM
+	query := `SELECT id, id_problem, id_submission, test_no, verdict, time_elapsed, memory_used, score, time_samples FROM
        {{.TablePrefix}}testresults WHERE id_submission = ? ORDER BY test_no ASC`
 	prep, err := db.Prepare(query)
 	if err != nil {
@@ -182,6 +260,7 @@ func (sdm *SubmissionDbModel) GetTestResultOfSubmission(submissionId int) ([]gyt
 	var trl []gytypes.TestResultData
 	for rows.Next() {
 		tr := gytypes.TestResultData{}
+		var timeSamples string
 		err = rows.Scan(
 			&tr.Id,
 			&tr.ProblemId,
@@ -191,19 +270,21 @@ func (sdm *SubmissionDbModel) GetTestResultOfSubmission(submissionId int) ([]gyt
 			&tr.TimeElapsed,
 			&tr.MemoryUsed,
 			&tr.Score,
+			&timeSamples,
 		)
 		if err != nil {
 			return nil, err
 		}
+		tr.TimeSamples = decodeTimeSamples(timeSamples)
 		trl = append(trl, tr)
 	}
 	return trl, nil
 }
 
-func (sdm *SubmissionDbModel) InsertSubmissionOnQueue(idProblem, idUser, idLang int, code string) (int, error) {
+func (sdm *SubmissionDbModel) InsertSubmissionOnQueue(idProblem, idUser, idLang int, code string, priority int) (int, error) {
 	db := sdm.db
-	query := `INSERT INTO {{.TablePrefix}}submissions (id_problem, id_user, id_lang, code, verdict, details, submit_time, compile_stdout, compile_stderr)
-        VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	query := `INSERT INTO {{.TablePrefix}}submissions (id_problem, id_user, id_lang, code, verdict, details, submit_time, compile_stdout, compile_stderr,
+        judge_state, judge_priority) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
 	prep, err := db.Prepare(query)
 	if err != nil {
 		return 0, err
@@ -220,6 +301,8 @@ func (sdm *SubmissionDbModel) InsertSubmissionOnQueue(idProblem, idUser, idLang
 		now,
 		"",
 		"",
+		gytypes.JudgeStateQueued,
+		priority,
 	)
 	if err != nil {
 		return 0, err
@@ -233,8 +316,8 @@ func (sdm *SubmissionDbModel) InsertSubmissionOnQueue(idProblem, idUser, idLang
 
 func (sdm *SubmissionDbModel) InsertTestResult(testResult gytypes.TestResultData) error {
 	db := sdm.db
-	query := `INSERT INTO {{.TablePrefix}}testresults (id_problem, id_submission, test_no, verdict, time_elapsed, memory_used, score)
-        VALUES (?, ?, ?, ?, ?, ?, ?)`
+	query := `INSERT INTO {{.TablePrefix}}testresults (id_problem, id_submission, test_no, verdict, time_elapsed, memory_used, score, time_samples)
+        VALUES (?, ?, ?, ?, ?, ?, ?, ?)`
 	stmt, err := db.Prepare(query)
 	if err != nil {
 		return err
@@ -248,10 +331,43 @@ func (sdm *SubmissionDbModel) InsertTestResult(testResult gytypes.TestResultData
 		testResult.TimeElapsed,
 		testResult.MemoryUsed,
 		testResult.Score,
+		encodeTimeSamples(testResult.TimeSamples),
 	)
 	return err
 }
 
+// Size of time_samples column
+const timeSamplesMaxLen = 200
+
+// Time samples stored as comma separated milliseconds, later ones dropped if not fit in column
+func encodeTimeSamples(samples []float64) string {
+	result := ""
+	for _, v := range samples {
+		sample := strconv.FormatFloat(v, 'f', 3, 64)
+		if result != "" {
+			sample = "," + sample
+		}
+		if len(result)+len(sample) > timeSamplesMaxLen {
+			break
+		}
+		result += sample
+	}
+	return result
+}
+
+func decodeTimeSamples(samples string) []float64 {
+	var result []float64
+	if samples == "" {
+		return result
+	}
+	for _, v := range strings.Split(samples, ",") {
+		if f, err := strconv.ParseFloat(v, 64); err == nil {
+			result = append(result, f)
+		}
+	}
+	return result
+}
+
 func sanitizePath(log string) string {
 	progDir := gylib.GetProgramBaseDir()
 	cachesDir := progDir + "/lib/caches"
@@ -286,3 +402,474 @@ func (sdm *SubmissionDbModel) UpdateSubmission(id int, submission gytypes.Submis
 	)
 	return err
 }
+
+// Get queued submissions, higher priority first then by submission order
+func (sdm *SubmissionDbModel) GetQueuedSubmissions() ([]gytypes.JudgeQueueData, error) {
+	db := sdm.db
+	query := `SELECT id, id_problem, id_user, id_lang, submit_time, judge_state, judge_priority FROM {{.TablePrefix}}submissions
+        WHERE judge_state = ? ORDER BY judge_priority DESC, id ASC`
+	stmt, err := db.Prepare(query)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+	rows, err := stmt.Query(gytypes.JudgeStateQueued)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var queue []gytypes.JudgeQueueData
+	for rows.Next() {
+		item := gytypes.JudgeQueueData{}
+		var utSubmitTime int64
+		err = rows.Scan(
+			&item.SubmissionId,
+			&item.ProblemId,
+			&item.UserId,
+			&item.LanguageId,
+			&utSubmitTime,
+			&item.JudgeState,
+			&item.Priority,
+		)
+		if err != nil {
+			return nil, err
+		}
+		item.SubmitTime = time.Unix(utSubmitTime, 0).Local()
+		queue = append(queue, item)
+	}
+	return queue, nil
+}
+
+// Take queued submission for judging, false if already taken by another worker
+func (sdm *SubmissionDbModel) ClaimQueuedSubmission(id int, leaseTime int64) (bool, error) {
+	db := sdm.db
+	query := `UPDATE {{.TablePrefix}}submissions SET judge_state = ?, lease_time = ? WHERE (id = ?) AND (judge_state = ?)`
+	stmt, err := db.Prepare(query)
+	if err != nil {
+		return false, err
+	}
+	defer stmt.Close()
+	res, err := stmt.Exec(gytypes.JudgeStateJudging, leaseTime, id, gytypes.JudgeStateQueued)
+	if err != nil {
+		return false, err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected == 1, nil
+}
+
+// Mark submission as cancelled only if still in given judge state, returns false if state already changed
+func (sdm *SubmissionDbModel) CancelSubmission(id int, fromState string) (bool, error) {
+	db := sdm.db
+	query := `UPDATE {{.TablePrefix}}submissions SET verdict = ?, details = ?, judge_state = ?, lease_time = 0
+        WHERE (id = ?) AND (judge_state = ?)`
+	stmt, err := db.Prepare(query)
+	if err != nil {
+		return false, err
+	}
+	defer stmt.Close()
+	res, err := stmt.Exec(gytypes.SubmissionCancelled, gytypes.TranslateSubmissionCode(gytypes.SubmissionCancelled),
+		gytypes.JudgeStateCancelled, id, fromState)
+	if err != nil {
+		return false, err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected == 1, nil
+}
+
+func (sdm *SubmissionDbModel) RenewSubmissionLease(id int, leaseTime int64) error {
+	db := sdm.db
+	query := `UPDATE {{.TablePrefix}}submissions SET lease_time = ? WHERE (id = ?) AND (judge_state = ?)`
+	stmt, err := db.Prepare(query)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	_, err = stmt.Exec(leaseTime, id, gytypes.JudgeStateJudging)
+	return err
+}
+
+// Set judge state once judging finished, along with slave judged it and finish time
+func (sdm *SubmissionDbModel) SetSubmissionJudgeState(id int, state string, judgedBy string) error {
+	db := sdm.db
+	query := `UPDATE {{.TablePrefix}}submissions SET judge_state = ?, lease_time = 0, judged_by = ?, judged_time = ?
+        WHERE id = ?`
+	stmt, err := db.Prepare(query)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	_, err = stmt.Exec(state, judgedBy, time.Now().Unix(), id)
+	return err
+}
+
+// Put back submissions which judging lease was expired before given time, returns affected count
+func (sdm *SubmissionDbModel) RequeueExpiredSubmissions(expireTime int64) (int64, error) {
+	db := sdm.db
+	query := `UPDATE {{.TablePrefix}}submissions SET judge_state = ?, lease_time = 0
+        WHERE (judge_state = ?) AND (lease_time < ?)`
+	stmt, err := db.Prepare(query)
+	if err != nil {
+		return 0, err
+	}
+	defer stmt.Close()
+	res, err := stmt.Exec(gytypes.JudgeStateQueued, gytypes.JudgeStateJudging, expireTime)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// Count of finished judging per slave address since given time, old submissions rejudged lately counted too
+func (sdm *SubmissionDbModel) GetJudgedCountBySlave(since int64) (map[string]int, error) {
+	db := sdm.db
+	query := `SELECT judged_by, COUNT(*) FROM {{.TablePrefix}}submissions WHERE (judge_state = ?) AND (judged_time >= ?)
+        GROUP BY judged_by`
+	stmt, err := db.Prepare(query)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+	rows, err := stmt.Query(gytypes.JudgeStateDone, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	counts := make(map[string]int)
+	for rows.Next() {
+		var address string
+		var count int
+		if err = rows.Scan(&address, &count); err != nil {
+			return nil, err
+		}
+		counts[address] = count
+	}
+	return counts, nil
+}
+
+func (sdm *SubmissionDbModel) GetSubmissionIdsForRejudge(filter gytypes.RejudgeFilter) ([]int, error) {
+	db := sdm.db
+	// Queued or currently judged submission will be judged anyway
+	query := `SELECT s.id FROM {{.TablePrefix}}submissions AS s
+        INNER JOIN {{.TablePrefix}}problems AS p ON s.id_problem = p.id
+        WHERE ((s.id = ?) OR (0 = ?)) AND ((s.id_problem = ?) OR (0 = ?)) AND ((p.contest_id = ?) OR (0 = ?))
+        AND ((s.id_lang = ?) OR (0 = ?)) AND ((s.verdict = ?) OR ('' = ?)) AND (s.judge_state IN (?, ?, ?))
+        ORDER BY s.id ASC`
+	stmt, err := db.Prepare(query)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+	rows, err := stmt.Query(
+		filter.SubmissionId, filter.SubmissionId,
+		filter.ProblemId, filter.ProblemId,
+		filter.ContestId, filter.ContestId,
+		filter.LanguageId, filter.LanguageId,
+		filter.Verdict, filter.Verdict,
+		gytypes.JudgeStateDone, gytypes.JudgeStateFailed, gytypes.JudgeStateCancelled,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err = rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// Discard previous judging result and put submission back into judge queue
+func (sdm *SubmissionDbModel) ResetSubmissionForRejudge(id int) error {
+	tx, err := sdm.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	// Queued or judging one left untouched, including its test results
+	query := `UPDATE {{.TablePrefix}}submissions SET verdict = ?, details = ?, score = 0, compile_time = 0,
+        compile_stdout = ?, compile_stderr = ?, judge_state = ?, lease_time = 0, regraded = 1, judge_priority = ?
+        WHERE (id = ?) AND (judge_state IN (?, ?, ?, ?))`
+	stmt, err := tx.Prepare(query)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	res, err := stmt.Exec(
+		gytypes.SubmissionOnQueue,
+		"",
+		"",
+		"",
+		gytypes.JudgeStateQueued,
+		gytypes.JudgePriorityLow,
+		id,
+		gytypes.JudgeStateDone,
+		gytypes.JudgeStateFailed,
+		gytypes.JudgeStateCancelled,
+		gytypes.JudgeStateHeld,
+	)
+	if err != nil {
+		return err
+	}
+	if affected, err := res.RowsAffected(); err != nil {
+		return err
+	} else if affected == 0 {
+		return errors.New("submission is not in a rejudgeable state")
+	}
+	queryResults := `DELETE FROM {{.TablePrefix}}testresults WHERE id_submission = ?`
+	stmtResults, err := tx.Prepare(queryResults)
+	if err != nil {
+		return err
+	}
+	defer stmtResults.Close()
+	if _, err = stmtResults.Exec(id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Finished judging of user on a problem, ordered as submitted
+func (sdm *SubmissionDbModel) GetJudgedSubmissionsOfUser(problemId, userId int) ([]gytypes.ScoreSubmissionData, error) {
+	db := sdm.db
+	query := `SELECT id, id_problem, id_user, verdict, score, submit_time, regraded FROM {{.TablePrefix}}submissions
+        WHERE (id_problem = ?) AND (id_user = ?) AND (judge_state = ?) ORDER BY id ASC`
+	stmt, err := db.Prepare(query)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+	rows, err := stmt.Query(problemId, userId, gytypes.JudgeStateDone)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var history []gytypes.ScoreSubmissionData
+	for rows.Next() {
+		ss := gytypes.ScoreSubmissionData{}
+		err = rows.Scan(
+			&ss.SubmissionId,
+			&ss.ProblemId,
+			&ss.UserId,
+			&ss.Verdict,
+			&ss.Score,
+			&ss.SubmitTime,
+			&ss.Regraded,
+		)
+		if err != nil {
+			return nil, err
+		}
+		history = append(history, ss)
+	}
+	return history, nil
+}
+
+// Get judged submissions of all users on a contest, ordered by submission
+func (sdm *SubmissionDbModel) GetJudgedSubmissionsOfContest(contestId int) ([]gytypes.ScoreSubmissionData, error) {
+	db := sdm.db
+	query := `SELECT s.id, s.id_problem, s.id_user, s.verdict, s.score, s.submit_time, s.regraded
+        FROM {{.TablePrefix}}submissions AS s INNER JOIN {{.TablePrefix}}problems AS p ON p.id = s.id_problem
+        WHERE (p.contest_id = ?) AND (s.judge_state = ?) ORDER BY s.id ASC`
+	stmt, err := db.Prepare(query)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+	rows, err := stmt.Query(contestId, gytypes.JudgeStateDone)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var history []gytypes.ScoreSubmissionData
+	for rows.Next() {
+		ss := gytypes.ScoreSubmissionData{}
+		err = rows.Scan(
+			&ss.SubmissionId,
+			&ss.ProblemId,
+			&ss.UserId,
+			&ss.Verdict,
+			&ss.Score,
+			&ss.SubmitTime,
+			&ss.Regraded,
+		)
+		if err != nil {
+			return nil, err
+		}
+		history = append(history, ss)
+	}
+	return history, nil
+}
+
+// Get submissions of all users on a contest without source code, ordered by submission
+func (sdm *SubmissionDbModel) GetSubmissionsOfContest(contestId int) ([]gytypes.SubmissionData, error) {
+	db := sdm.db
+	query := `SELECT s.id, s.id_problem, s.id_user, s.id_lang, s.verdict, s.score, s.submit_time, s.judge_state, s.regraded
+        FROM {{.TablePrefix}}submissions AS s INNER JOIN {{.TablePrefix}}problems AS p ON p.id = s.id_problem
+        WHERE p.contest_id = ? ORDER BY s.id ASC`
+	stmt, err := db.Prepare(query)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+	rows, err := stmt.Query(contestId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var subs []gytypes.SubmissionData
+	for rows.Next() {
+		sb := gytypes.SubmissionData{}
+		var utSubmitTime int64
+		err = rows.Scan(
+			&sb.Id,
+			&sb.ProblemId,
+			&sb.UserId,
+			&sb.LanguageId,
+			&sb.Verdict,
+			&sb.Score,
+			&utSubmitTime,
+			&sb.JudgeState,
+			&sb.Regraded,
+		)
+		if err != nil {
+			return nil, err
+		}
+		sb.SubmitTime = time.Unix(utSubmitTime, 0).Local()
+		subs = append(subs, sb)
+	}
+	return subs, nil
+}
+
+// Record disagreement between two slaves on same submission
+func (sdm *SubmissionDbModel) InsertJudgeMismatch(md gytypes.JudgeMismatchData) (int, error) {
+	db := sdm.db
+	query := `INSERT INTO {{.TablePrefix}}judge_mismatches (id_submission, slave_a, verdict_a, score_a, result_a,
+        slave_b, verdict_b, score_b, result_b, create_time, resolution, resolved_by, resolve_time)
+        VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, 0, 0)`
+	prep, err := db.Prepare(query)
+	if err != nil {
+		return 0, err
+	}
+	defer prep.Close()
+	res, err := prep.Exec(
+		md.SubmissionId,
+		md.SlaveA,
+		md.VerdictA,
+		md.ScoreA,
+		md.ResultA,
+		md.SlaveB,
+		md.VerdictB,
+		md.ScoreB,
+		md.ResultB,
+		time.Now().Unix(),
+		"",
+	)
+	if err != nil {
+		return 0, err
+	}
+	idx, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	return int(idx), nil
+}
+
+const judgeMismatchSelect = `SELECT m.id, m.id_submission, m.slave_a, m.verdict_a, m.score_a, m.result_a, m.slave_b,
+        m.verdict_b, m.score_b, m.result_b, m.create_time, m.resolution, m.resolved_by, m.resolve_time,
+        u.display_name, p.problem_name
+        FROM (({{.TablePrefix}}judge_mismatches AS m INNER JOIN {{.TablePrefix}}submissions AS s ON m.id_submission = s.id)
+        INNER JOIN {{.TablePrefix}}users AS u ON s.id_user = u.id)
+        INNER JOIN {{.TablePrefix}}problems AS p ON s.id_problem = p.id`
+
+func scanJudgeMismatch(scan func(dest ...interface{}) error) (gytypes.JudgeMismatchData, error) {
+	md := gytypes.JudgeMismatchData{}
+	var utCreateTime, utResolveTime int64
+	err := scan(
+		&md.Id,
+		&md.SubmissionId,
+		&md.SlaveA,
+		&md.VerdictA,
+		&md.ScoreA,
+		&md.ResultA,
+		&md.SlaveB,
+		&md.VerdictB,
+		&md.ScoreB,
+		&md.ResultB,
+		&utCreateTime,
+		&md.Resolution,
+		&md.ResolvedBy,
+		&utResolveTime,
+		&md.UserDisplayName,
+		&md.ProblemName,
+	)
+	if err != nil {
+		return md, err
+	}
+	md.CreateTime = time.Unix(utCreateTime, 0).Local()
+	md.ResolveTime = time.Unix(utResolveTime, 0).Local()
+	return md, nil
+}
+
+// Get judge mismatches, unresolved ones listed first
+func (sdm *SubmissionDbModel) GetJudgeMismatchList() ([]gytypes.JudgeMismatchData, error) {
+	db := sdm.db
+	query := judgeMismatchSelect + ` ORDER BY CASE WHEN m.resolution = '' THEN 0 ELSE 1 END ASC, m.id DESC`
+	stmt, err := db.Prepare(query)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+	rows, err := stmt.Query()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var list []gytypes.JudgeMismatchData
+	for rows.Next() {
+		md, err := scanJudgeMismatch(rows.Scan)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, md)
+	}
+	return list, nil
+}
+
+func (sdm *SubmissionDbModel) GetJudgeMismatchById(id int) (gytypes.JudgeMismatchData, error) {
+	db := sdm.db
+	stmt, err := db.Prepare(judgeMismatchSelect + ` WHERE m.id = ?`)
+	if err != nil {
+		return gytypes.JudgeMismatchData{}, err
+	}
+	defer stmt.Close()
+	return scanJudgeMismatch(stmt.QueryRow(id).Scan)
+}
+
+// Mark mismatch as resolved, returns false if already resolved by someone else
+func (sdm *SubmissionDbModel) ResolveJudgeMismatch(id int, resolution string, resolvedBy int) (bool, error) {
+	db := sdm.db
+	query := `UPDATE {{.TablePrefix}}judge_mismatches SET resolution = ?, resolved_by = ?, resolve_time = ?
+        WHERE (id = ?) AND (resolution = '')`
+	stmt, err := db.Prepare(query)
+	if err != nil {
+		return false, err
+	}
+	defer stmt.Close()
+	res, err := stmt.Exec(resolution, resolvedBy, time.Now().Unix(), id)
+	if err != nil {
+		return false, err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected > 0, nil
+}
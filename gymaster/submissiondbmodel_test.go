@@ -0,0 +1,35 @@
+package main
+
+/* GargoyleJudge - Simple Judgement System for Competitive Programming
+ * Copyright (C) Thiekus 2019
+ * Visit www.khayalan.id for updates
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/. */
+
+import (
+	"testing"
+)
+
+func TestEncodeTimeSamples(t *testing.T) {
+	if got := encodeTimeSamples([]float64{980.5, 1010, 995.25}); got != "980.500,1010.000,995.250" {
+		t.Errorf("encodeTimeSamples = %q", got)
+	}
+	if got := decodeTimeSamples(encodeTimeSamples(nil)); len(got) != 0 {
+		t.Errorf("no samples decoded as %v", got)
+	}
+	// Never exceeds column even on huge re-run count
+	var samples []float64
+	for i := 0; i < 50; i++ {
+		samples = append(samples, 123456.789)
+	}
+	encoded := encodeTimeSamples(samples)
+	if len(encoded) > timeSamplesMaxLen {
+		t.Errorf("encoded %d chars, column holds %d", len(encoded), timeSamplesMaxLen)
+	}
+	decoded := decodeTimeSamples(encoded)
+	if (len(decoded) == 0) || (decoded[0] != samples[0]) {
+		t.Errorf("first sample lost, decoded %v", decoded)
+	}
+}
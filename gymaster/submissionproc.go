@@ -11,24 +11,42 @@ package main
 import (
 	"errors"
 	"fmt"
-	"strconv"
+	"time"
 
-	"github.com/thiekus/gargoyle-judge/internal/gylib"
-	"github.com/thiekus/gargoyle-judge/internal/gyrpc"
 	"github.com/thiekus/gargoyle-judge/internal/gytypes"
 )
 
 type SubmissionProcessor struct {
-	slaveMan     *SlaveManager
+	judgeQueue   *JudgeQueue
 	problem      gytypes.ProblemData
 	submissionId int
 	problemId    int
 	userId       int
 	langId       int
 	code         string
+	priority     int
 }
 
-func NewSubmissionProcessor(slaveMan *SlaveManager, idProblem, idUser, idLang int, code string) (*SubmissionProcessor, error) {
+// Decide judging priority class of new submission
+func getSubmissionPriority(userId int, contest gytypes.ContestData, ca *gytypes.ContestAccess) int {
+	// Jury and admin submissions are test runs
+	if ui := appUsers.GetUserById(userId); (ui != nil) && (ui.IsJury() || ui.IsAdmin()) {
+		return gytypes.JudgePriorityLow
+	}
+	now := time.Now().Unix()
+	utStartTime := contest.StartTime.Unix()
+	utEndTime := contest.EndTime.Unix()
+	if (utStartTime > 0) && (utEndTime > 0) && (now >= utStartTime) && (now <= utEndTime) {
+		return gytypes.JudgePriorityHigh
+	}
+	// Timed attempt on unlimited contest also competing
+	if ca.EndTime.Unix() > 0 {
+		return gytypes.JudgePriorityHigh
+	}
+	return gytypes.JudgePriorityNormal
+}
+
+func NewSubmissionProcessor(judgeQueue *JudgeQueue, idProblem, idUser, idLang int, code string) (*SubmissionProcessor, error) {
 	db, err := OpenDatabase()
 	if err != nil {
 		return nil, err
@@ -48,7 +66,22 @@ func NewSubmissionProcessor(slaveMan *SlaveManager, idProblem, idUser, idLang in
 	if err != nil {
 		return nil, err
 	}
+	contest, err := cdm.GetContestDetails(problem.ContestId)
+	if err != nil {
+		return nil, err
+	}
 	sdm := NewSubmissionDbModel(db)
+	priority := getSubmissionPriority(idUser, contest, ca)
+	// Throttle submitters, except jury test runs
+	if (appConfig.SubmitCooldown > 0) && (priority != gytypes.JudgePriorityLow) {
+		lastTime, err := sdm.GetLastSubmitTime(idUser)
+		if err != nil {
+			return nil, err
+		}
+		if wait := lastTime + int64(appConfig.SubmitCooldown) - time.Now().Unix(); wait > 0 {
+			return nil, fmt.Errorf("please wait %d second(s) before submitting again", wait)
+		}
+	}
 	// Check if problem have maximum attempts
 	if problem.MaxAttempts > 0 {
 		count, err := sdm.GetSubmissionCount(idUser, idProblem)
@@ -61,127 +94,32 @@ func NewSubmissionProcessor(slaveMan *SlaveManager, idProblem, idUser, idLang in
 		}
 	}
 	sp := SubmissionProcessor{
-		slaveMan:     slaveMan,
+		judgeQueue:   judgeQueue,
 		submissionId: 0,
 		problem:      problem,
 		problemId:    idProblem,
 		userId:       idUser,
 		langId:       idLang,
 		code:         code,
+		priority:     priority,
 	}
 	return &sp, nil
 }
 
-func (sp *SubmissionProcessor) processSubmission(client *gyrpc.GargoyleRpcClient, db *DbContext) {
-	defer client.Close()
-	defer db.Close()
-	sdm := NewSubmissionDbModel(*db)
-	log := gylib.GetStdLog()
-	sub := gytypes.SubmissionData{
-		Id:      sp.submissionId,
-		Verdict: gytypes.SubmissionError,
-		Score:   0,
-	}
-	// deferred conclusion of this process
-	defer func() {
-		if sub.Details == "" {
-			sub.Details = sub.GetStatusMessage()
-		} else {
-			sub.Details = sub.GetStatusMessage() + ": " + sub.Details
-		}
-		log.Printf("Submission [id:%d, verdict:%s]: %s", sub.Id, sub.Verdict, sub.Details)
-		// Since db not yet closed, we still can doing db operations
-		// Update score
-		var err error = nil
-		if sub.Verdict == gytypes.SubmissionAccepted {
-			err = appScoreboard.SubmitScore(sub.ProblemId, sub.UserId, sub.Score, true)
-		} else if (sub.Verdict != gytypes.SubmissionCompilerError) && (sub.Verdict != gytypes.SubmissionOnQueue) &&
-			(sub.Verdict != gytypes.SubmissionError) {
-			err = appScoreboard.SubmitScore(sub.ProblemId, sub.UserId, sub.Score, false)
-		}
-		if err != nil {
-			log.Errorf("Error while updating score for id %d", sub.Id)
-			return
-		}
-		if err = sdm.UpdateSubmission(sub.Id, sub); err != nil {
-			log.Errorf("Error while updating submission for id %d", sub.Id)
-			return
-		}
-		desc := fmt.Sprintf("Your last submission graded as %s (%s)", sub.Verdict, sub.GetStatusMessage())
-		link := "/dashboard/userViewSubmission/" + strconv.Itoa(sub.Id)
-		if err = appNotifications.AddNotification(sub.UserId, 0, desc, link); err != nil {
-			log.Errorf("Error while updating notification for id %d", sub.Id)
-			return
-		}
-	}()
-	sbTemp, err := sdm.GetSubmission(sp.submissionId)
-	if err != nil {
-		sub.Details = err.Error()
-		return
-	}
-	// Replace with gathered sub variable
-	sub = sbTemp
-	sub.Verdict = gytypes.SubmissionError
-	// Get Programming language data
-	lang, err := appLangPrograms.GetLanguageFromId(sub.LanguageId)
-	if err != nil {
-		sub.Details = err.Error()
-		return
-	}
-	// Get Problem details
-	cdm := NewContestDbModel(*db)
-	prob, err := cdm.GetProblemById(sub.ProblemId)
-	if err != nil {
-		sub.Details = err.Error()
-		return
-	}
-	// Retrieve test case for current problem for checking
-	tests, err := sdm.GetTestCasesOfProblem(sp.problemId)
-	if err != nil {
-		sub.Details = err.Error()
-		return
-	}
-	// Hit the slave to check it
-	resp, err := client.ProcessSubmission(sub, *lang, prob, tests)
-	if err != nil {
-		sub.Details = err.Error()
-		return
-	}
-	// Insert new rows for test case run result
-	for _, testCase := range resp.TestResults {
-		if err = sdm.InsertTestResult(testCase); err == nil {
-			log.Printf("Inserting %v", testCase)
-		} else {
-			log.Errorf("TestCase insert error: %s", err.Error())
-		}
-	}
-	sub = resp.Submission
-}
-
 func (sp *SubmissionProcessor) DoProcess() error {
-	sl, err := sp.slaveMan.GetActiveSlave()
-	if err != nil {
-		return err
-	}
-	client, err := gyrpc.NewGargoyleRpcClient(sl.Address)
-	if err != nil {
-		return err
-	}
 	// Initialize Database connection and Submission database model
 	db, err := OpenDatabase()
 	if err != nil {
-		client.Close()
 		return err
 	}
+	defer db.Close()
 	sdm := NewSubmissionDbModel(db)
-	// Insert into submission queue
-	subId, err := sdm.InsertSubmissionOnQueue(sp.problemId, sp.userId, sp.langId, sp.code)
+	// Insert into submission queue, judged later by queue workers
+	subId, err := sdm.InsertSubmissionOnQueue(sp.problemId, sp.userId, sp.langId, sp.code, sp.priority)
 	if err != nil {
-		db.Close()
-		client.Close()
 		return err
 	}
 	sp.submissionId = subId
-	go sp.processSubmission(client, &db)
+	sp.judgeQueue.Notify()
 	return nil
 }
@@ -9,131 +9,54 @@ package main
  * file, You can obtain one at https://mozilla.org/MPL/2.0/. */
 
 import (
-	"bytes"
+	"flag"
 	"fmt"
-	"io/ioutil"
 	"os"
-	"os/exec"
+	"os/signal"
 	"runtime"
-	"strings"
+	"syscall"
 	"time"
 
-	"github.com/shirou/gopsutil/process"
 	"github.com/thiekus/gargoyle-judge/internal/gylib"
 	"github.com/thiekus/gargoyle-judge/internal/gyrpc"
+	"github.com/thiekus/gargoyle-judge/internal/gytask"
 )
 
 const appVersion = "0.7r69"
 
 var appOSName string
 
-type SlaveTaskHandler struct{}
-
-func (sth SlaveTaskHandler) SlaveSaveCode(code string, sourceName string) (string, error) {
-	var tempDir string
+// Keep registration alive, register again if master forgot about us
+func runHeartbeat(client *gyrpc.GargoyleRegisterClient, interval time.Duration, quit chan bool) {
+	log := gylib.GetStdLog()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
 	for {
-		tempDir = fmt.Sprintf("%s/%s", gylib.GetCacheDir(), gylib.GenerateRandomSalt())
-		if !gylib.IsDirectoryExists(tempDir) {
-			break
+		select {
+		case <-quit:
+			return
+		case <-ticker.C:
 		}
-	}
-	err := os.Mkdir(tempDir, os.ModePerm)
-	if err != nil {
-		return "", err
-	}
-	codePath := tempDir + "/" + sourceName
-	err = ioutil.WriteFile(codePath, []byte(code), os.ModePerm)
-	if err != nil {
-		return "", err
-	}
-	return tempDir, nil
-}
-
-func (sth SlaveTaskHandler) SlaveCompileCode(args []string, dir string) (float64, string, string, error) {
-	var cmd *exec.Cmd
-	if len(args) > 1 {
-		cmd = exec.Command(args[0], args[1:]...)
-	} else {
-		cmd = exec.Command(args[0])
-	}
-	var stdout, stderr bytes.Buffer
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
-	cmd.Dir = dir
-	startTime := time.Now()
-	err := cmd.Run()
-	duration := time.Since(startTime)
-	strStdout := stdout.String()
-	strStderr := stderr.String()
-	durationMs := float64(duration) / float64(time.Millisecond)
-	return durationMs, strStdout, strStderr, err
-}
-
-func (sth SlaveTaskHandler) SlaveRunCode(args []string, dir string, stdin string, timeout int) (float64, uint64, string, string, error) {
-	var cmd *exec.Cmd
-	if len(args) > 1 {
-		cmd = exec.Command(args[0], args[1:]...)
-	} else {
-		cmd = exec.Command(args[0])
-	}
-	//
-	stdinBuf := bytes.Buffer{}
-	stdinArr := strings.Split(stdin, "\n")
-	for k, v := range stdinArr {
-		stdinArr[k] = strings.ReplaceAll(v, "\r", "")
-		stdinBuf.Write([]byte(stdinArr[k] + "\n"))
-	}
-	var stdout, stderr bytes.Buffer
-	cmd.Stdin = &stdinBuf
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
-	cmd.Dir = dir
-	memoryPeakUsage := uint64(0)
-	done := make(chan error)
-	if err := cmd.Start(); err != nil {
-		return 0, 0, "", "", err
-	}
-	startTime := time.Now()
-	running := true
-	// Goroutine for measuring memory peak usage
-	pid := cmd.Process.Pid
-	go func(pid int) {
-		for running {
-			if proc, err := process.NewProcess(int32(pid)); err == nil {
-				if mem, err := proc.MemoryInfo(); err == nil {
-					memUsage := mem.RSS
-					if memUsage > memoryPeakUsage {
-						memoryPeakUsage = memUsage
-					}
-				}
-			}
-			time.Sleep(10 * time.Millisecond)
+		_, err := client.Heartbeat()
+		if err == gyrpc.ErrSlaveNotRegistered {
+			log.Warn("Master doesn't know this slave, registering again...")
+			_, err = client.Register()
+		}
+		if err != nil {
+			log.Errorf("Heartbeat to master failed: %s", err.Error())
 		}
-	}(pid)
-	// Wait until finishes
-	go func() {
-		done <- cmd.Wait()
-	}()
-	var duration time.Duration
-	var err error
-	select {
-	case <-time.After(time.Duration(timeout) * time.Second):
-		duration = time.Since(startTime)
-		err = cmd.Process.Kill()
-
-	case err = <-done:
-		duration = time.Since(startTime)
 	}
-	running = false
-	durationMs := float64(duration) / float64(time.Millisecond)
-	return durationMs, memoryPeakUsage, stdout.String(), stderr.String(), err
-}
-
-func (sth SlaveTaskHandler) SlaveFinishProcess(dir string) error {
-	return os.RemoveAll(dir)
 }
 
 func main() {
+	listenAddr := flag.String("listen", ":28499", "RPC listening address")
+	masterUrl := flag.String("master", "", "master base URL to self-register into, e.g. http://master:28498")
+	token := flag.String("token", "", "slave registration token configured on master")
+	name := flag.String("name", "", "slave name shown on master (default hostname)")
+	advertise := flag.String("advertise", "", "address master uses to reach this slave (default host seen by master with listening port)")
+	heartbeat := flag.Int("heartbeat", 30, "heartbeat interval to master in seconds")
+	flag.Parse()
+
 	fmt.Printf("Gargoyle Judgement System v%s (Slave Server)\n", appVersion)
 	fmt.Println("Copyright (C) Thiekus 2019")
 	fmt.Printf("Built using %s\n", runtime.Version())
@@ -146,12 +69,56 @@ func main() {
 
 	log := gylib.GetStdLog()
 	log.Print("Initializing slave server...")
-	server, err := gyrpc.NewGargoyleRpcServer(":28499", SlaveTaskHandler{})
+	server, err := gyrpc.NewGargoyleRpcServer(*listenAddr, gytask.SlaveTaskHandler{})
 	if err != nil {
 		panic(err)
 	}
-	log.Print("Listening and serve RPC Server...")
-	if err := server.ListenAndServe(); err != nil {
+	if *masterUrl == "" {
+		log.Print("Listening and serve RPC Server...")
+		if err := server.ListenAndServe(); err != nil {
+			panic(err)
+		}
+		return
+	}
+	// Announce ourselves to master while serving
+	serveErr := make(chan error, 1)
+	go func() {
+		log.Print("Listening and serve RPC Server...")
+		serveErr <- server.ListenAndServe()
+	}()
+	if *name == "" {
+		if hostname, err := os.Hostname(); err == nil {
+			*name = hostname
+		}
+	}
+	address := *advertise
+	if address == "" {
+		address = *listenAddr
+	}
+	regClient := gyrpc.NewGargoyleRegisterClient(*masterUrl, *token, *name, address)
+	if resp, err := regClient.Register(); err != nil {
+		log.Errorf("Cannot register to master: %s", err.Error())
+	} else if !resp.Approved {
+		log.Warnf("Registered to master as %s, waiting for admin approval", resp.Address)
+	} else {
+		log.Printf("Registered to master as %s", resp.Address)
+	}
+	quit := make(chan bool)
+	go runHeartbeat(regClient, time.Duration(*heartbeat)*time.Second, quit)
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	select {
+	case err = <-serveErr:
+	case sig := <-signals:
+		log.Printf("Received %s, shutting down...", sig)
+	}
+	close(quit)
+	if derr := regClient.Deregister(); derr != nil {
+		log.Errorf("Cannot deregister from master: %s", derr.Error())
+	} else {
+		log.Print("Deregistered from master")
+	}
+	if err != nil {
 		panic(err)
 	}
 }
@@ -52,14 +52,25 @@ func (grc *GargoyleRpcClient) PingSlave() (RpcPingResponse, error) {
 	return resp, err
 }
 
-func (grc *GargoyleRpcClient) ProcessSubmission(submission gytypes.SubmissionData, lang gytypes.LanguageProgramData, problem gytypes.ProblemData, tests []gytypes.TestCaseData) (RpcSubmissionResponse, error) {
+func (grc *GargoyleRpcClient) ProcessSubmission(submission gytypes.SubmissionData, lang gytypes.LanguageProgramData, problem gytypes.ProblemData, tests []gytypes.TestCaseData, timingRerun int, timingBand float64) (RpcSubmissionResponse, error) {
 	req := RpcSubmissionRequest{
 		Submission:     submission,
 		ProgramLang:    lang,
 		ProblemDetails: problem,
 		TestCases:      tests,
+		TimingRerun:    timingRerun,
+		TimingBand:     timingBand,
 	}
 	var resp RpcSubmissionResponse
 	err := grc.client.Call("GargoyleRpcTask.ProcessSubmission", req, &resp)
 	return resp, err
 }
+
+func (grc *GargoyleRpcClient) CancelSubmission(submissionId int) (RpcCancelResponse, error) {
+	req := RpcCancelRequest{
+		SubmissionId: submissionId,
+	}
+	var resp RpcCancelResponse
+	err := grc.client.Call("GargoyleRpcTask.CancelSubmission", req, &resp)
+	return resp, err
+}
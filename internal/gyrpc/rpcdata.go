@@ -32,6 +32,9 @@ type RpcPingResponse struct {
 	StartTime int64
 	EndTime   int64
 	Delta     int64
+	OSName    string // Slave capabilities
+	Arch      string
+	NumCPU    int
 }
 
 type RpcSubmissionRequest struct {
@@ -40,6 +43,8 @@ type RpcSubmissionRequest struct {
 	ProgramLang    gytypes.LanguageProgramData
 	ProblemDetails gytypes.ProblemData
 	TestCases      []gytypes.TestCaseData
+	TimingRerun    int     // Maximum runs of test case when timing is borderline
+	TimingBand     float64 // Fraction around time limit considered as borderline
 }
 
 type RpcSubmissionResponse struct {
@@ -47,3 +52,13 @@ type RpcSubmissionResponse struct {
 	Submission  gytypes.SubmissionData
 	TestResults []gytypes.TestResultData
 }
+
+type RpcCancelRequest struct {
+	RpcDefaultRequest
+	SubmissionId int
+}
+
+type RpcCancelResponse struct {
+	RpcDefaultResponse
+	Cancelled bool // False if submission not running on this slave
+}
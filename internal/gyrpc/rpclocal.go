@@ -0,0 +1,72 @@
+package gyrpc
+
+/* GargoyleJudge - Simple Judgement System for Competitive Programming
+ * Copyright (C) Thiekus 2019
+ * Visit www.khayalan.id for updates
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/. */
+
+import (
+	"time"
+
+	"github.com/thiekus/gargoyle-judge/internal/gytypes"
+)
+
+// Common client interface, implemented by remote RPC client and in-process local client
+type GargoyleClient interface {
+	Address() string
+	Close() error
+	PingSlave() (RpcPingResponse, error)
+	ProcessSubmission(submission gytypes.SubmissionData, lang gytypes.LanguageProgramData, problem gytypes.ProblemData, tests []gytypes.TestCaseData, timingRerun int, timingBand float64) (RpcSubmissionResponse, error)
+	CancelSubmission(submissionId int) (RpcCancelResponse, error)
+}
+
+// Client for embedded slave, calling task of server directly without network
+type GargoyleLocalClient struct {
+	server *GargoyleRpcServer
+}
+
+func NewGargoyleLocalClient(server *GargoyleRpcServer) *GargoyleLocalClient {
+	glc := GargoyleLocalClient{
+		server: server,
+	}
+	return &glc
+}
+
+func (glc *GargoyleLocalClient) Address() string {
+	return glc.server.Address()
+}
+
+func (glc *GargoyleLocalClient) Close() error {
+	return nil
+}
+
+func (glc *GargoyleLocalClient) PingSlave() (RpcPingResponse, error) {
+	req := RpcPingRequest{
+		StartTime: time.Now().UnixNano(),
+	}
+	var resp RpcPingResponse
+	err := glc.server.task.PingSlave(req, &resp)
+	return resp, err
+}
+
+func (glc *GargoyleLocalClient) ProcessSubmission(submission gytypes.SubmissionData, lang gytypes.LanguageProgramData, problem gytypes.ProblemData, tests []gytypes.TestCaseData, timingRerun int, timingBand float64) (RpcSubmissionResponse, error) {
+	req := RpcSubmissionRequest{
+		Submission:     submission,
+		ProgramLang:    lang,
+		ProblemDetails: problem,
+		TestCases:      tests,
+		TimingRerun:    timingRerun,
+		TimingBand:     timingBand,
+	}
+	return glc.server.ProcessLocalSubmission(req)
+}
+
+func (glc *GargoyleLocalClient) CancelSubmission(submissionId int) (RpcCancelResponse, error) {
+	req := RpcCancelRequest{
+		SubmissionId: submissionId,
+	}
+	return glc.server.CancelLocalSubmission(req)
+}
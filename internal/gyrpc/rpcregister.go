@@ -0,0 +1,97 @@
+package gyrpc
+
+/* GargoyleJudge - Simple Judgement System for Competitive Programming
+ * Copyright (C) Thiekus 2019
+ * Visit www.khayalan.id for updates
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/. */
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Master endpoints for slave self-registration
+const (
+	SlaveRegisterPath   = "/slaveapi/register"
+	SlaveHeartbeatPath  = "/slaveapi/heartbeat"
+	SlaveDeregisterPath = "/slaveapi/deregister"
+)
+
+var ErrSlaveNotRegistered = errors.New("slave is not registered on master")
+
+type SlaveRegisterRequest struct {
+	Token   string `json:"token"`
+	Name    string `json:"name"`
+	Address string `json:"address"` // Empty host will be taken from remote address
+}
+
+type SlaveRegisterResponse struct {
+	Id       int    `json:"id"`
+	Address  string `json:"address"`
+	Approved bool   `json:"approved"`
+}
+
+// Client used by slave to announce itself into master
+type GargoyleRegisterClient struct {
+	masterUrl string
+	request   SlaveRegisterRequest
+	client    http.Client
+}
+
+func NewGargoyleRegisterClient(masterUrl, token, name, address string) *GargoyleRegisterClient {
+	grc := GargoyleRegisterClient{
+		masterUrl: strings.TrimSuffix(masterUrl, "/"),
+		request: SlaveRegisterRequest{
+			Token:   token,
+			Name:    name,
+			Address: address,
+		},
+		client: http.Client{
+			Timeout: 10 * time.Second,
+		},
+	}
+	return &grc
+}
+
+func (grc *GargoyleRegisterClient) post(path string) (SlaveRegisterResponse, error) {
+	var resp SlaveRegisterResponse
+	data, err := json.Marshal(grc.request)
+	if err != nil {
+		return resp, err
+	}
+	httpResp, err := grc.client.Post(grc.masterUrl+path, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return resp, err
+	}
+	defer httpResp.Body.Close()
+	if httpResp.StatusCode == http.StatusNotFound {
+		return resp, ErrSlaveNotRegistered
+	}
+	if httpResp.StatusCode != http.StatusOK {
+		return resp, fmt.Errorf("master responded with %s", httpResp.Status)
+	}
+	err = json.NewDecoder(httpResp.Body).Decode(&resp)
+	return resp, err
+}
+
+func (grc *GargoyleRegisterClient) Register() (SlaveRegisterResponse, error) {
+	return grc.post(SlaveRegisterPath)
+}
+
+// Returns ErrSlaveNotRegistered if master forgot this slave
+func (grc *GargoyleRegisterClient) Heartbeat() (SlaveRegisterResponse, error) {
+	return grc.post(SlaveHeartbeatPath)
+}
+
+func (grc *GargoyleRegisterClient) Deregister() error {
+	_, err := grc.post(SlaveDeregisterPath)
+	return err
+}
@@ -23,13 +23,15 @@ type GargoyleRpcServerVars struct {
 	ExeName    string
 	SourceName string
 	WorkPath   string
-	MemLimit   int
+	TimeLimit  int // Effective time limit in milliseconds
+	MemLimit   int // Effective memory limit in MB
 }
 
 type GargoyleRpcServerTaskHandler interface {
 	SlaveSaveCode(code string, sourceName string) (string, error)
 	SlaveCompileCode(args []string, dir string) (float64, string, string, error)
-	SlaveRunCode(args []string, dir string, stdin string, timeout int) (float64, uint64, string, string, error)
+	SlaveRunCode(args []string, dir string, stdin string, timeout int) (float64, uint64, string, string, error) // timeout in milliseconds
+	SlaveKillProcess(dir string) error
 	SlaveFinishProcess(dir string) error
 }
 
@@ -47,9 +49,11 @@ func (grs *GargoyleRpcServer) SetTaskHandler(taskHandler GargoyleRpcServerTaskHa
 
 func NewGargoyleRpcServer(address string, taskHandler GargoyleRpcServerTaskHandler) (*GargoyleRpcServer, error) {
 	grs := GargoyleRpcServer{
-		address:     address,
-		server:      rpc.Server{},
-		task:        GargoyleRpcTask{},
+		address: address,
+		server:  rpc.Server{},
+		task: GargoyleRpcTask{
+			running: make(map[int]*rpcRunningSubmission),
+		},
 		taskHandler: taskHandler,
 	}
 	grs.task.parentServer = &grs
@@ -75,12 +79,26 @@ func (grs *GargoyleRpcServer) ListenAndServe() error {
 	return nil
 }
 
-func (grs *GargoyleRpcServer) ParseVars(sub gytypes.SubmissionData, lang gytypes.LanguageProgramData, workDir string, input string) string {
+// Process submission in-process without network round trip, using the same task as RPC call
+func (grs *GargoyleRpcServer) ProcessLocalSubmission(req RpcSubmissionRequest) (RpcSubmissionResponse, error) {
+	var resp RpcSubmissionResponse
+	err := grs.task.ProcessSubmission(req, &resp)
+	return resp, err
+}
+
+func (grs *GargoyleRpcServer) CancelLocalSubmission(req RpcCancelRequest) (RpcCancelResponse, error) {
+	var resp RpcCancelResponse
+	err := grs.task.CancelSubmission(req, &resp)
+	return resp, err
+}
+
+func (grs *GargoyleRpcServer) ParseVars(sub gytypes.SubmissionData, lang gytypes.LanguageProgramData, prob gytypes.ProblemData, workDir string, input string) string {
 	vars := GargoyleRpcServerVars{
 		ExeName:    lang.ExecutableName,
 		SourceName: lang.SourceName,
 		WorkPath:   workDir,
-		MemLimit:   32,
+		TimeLimit:  lang.GetTimeLimit(prob.TimeLimit),
+		MemLimit:   lang.GetMemLimit(prob.MemLimit),
 	}
 	// For windows, it's better if PE executable is exe file
 	if runtime.GOOS == "windows" {
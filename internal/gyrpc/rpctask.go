@@ -9,17 +9,55 @@ package gyrpc
  * file, You can obtain one at https://mozilla.org/MPL/2.0/. */
 
 import (
+	"errors"
 	"math"
 	"regexp"
+	"runtime"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/thiekus/gargoyle-judge/internal/gylib"
 	"github.com/thiekus/gargoyle-judge/internal/gytypes"
 )
 
+var ErrSubmissionCancelled = errors.New("submission cancelled")
+
+// Submission being processed, keyed by its id in task
+type rpcRunningSubmission struct {
+	workDir   string
+	cancelled bool
+}
+
 type GargoyleRpcTask struct {
 	parentServer *GargoyleRpcServer
+	running      map[int]*rpcRunningSubmission
+	runningMutex sync.Mutex
+}
+
+func (grt *GargoyleRpcTask) isCancelled(subId int) bool {
+	grt.runningMutex.Lock()
+	defer grt.runningMutex.Unlock()
+	if rs, exists := grt.running[subId]; exists {
+		return rs.cancelled
+	}
+	return false
+}
+
+// Kill running process of submission, its work dir cleaned after ProcessSubmission returned
+func (grt *GargoyleRpcTask) CancelSubmission(req RpcCancelRequest, resp *RpcCancelResponse) error {
+	log := gylib.GetStdLog()
+	grt.runningMutex.Lock()
+	defer grt.runningMutex.Unlock()
+	rs, exists := grt.running[req.SubmissionId]
+	if !exists {
+		resp.Cancelled = false
+		return nil
+	}
+	log.Printf("[subId:%d] Cancellation requested by master", req.SubmissionId)
+	rs.cancelled = true
+	resp.Cancelled = true
+	return grt.parentServer.taskHandler.SlaveKillProcess(rs.workDir)
 }
 
 func (grt *GargoyleRpcTask) PingSlave(req RpcPingRequest, resp *RpcPingResponse) error {
@@ -28,11 +66,34 @@ func (grt *GargoyleRpcTask) PingSlave(req RpcPingRequest, resp *RpcPingResponse)
 	resp.StartTime = req.StartTime
 	resp.EndTime = et
 	resp.Delta = et - req.StartTime
+	resp.OSName = runtime.GOOS
+	resp.Arch = runtime.GOARCH
+	resp.NumCPU = runtime.NumCPU()
 	delta := float64(resp.Delta) / float64(time.Millisecond)
 	log.Printf("Ping from master, delta %fms", delta)
 	return nil
 }
 
+// Check whether duration lies within band fraction around time limit
+func (grt *GargoyleRpcTask) isBorderlineTime(duration float64, timeLimit int, band float64) bool {
+	if band <= 0 {
+		return false
+	}
+	limit := float64(timeLimit)
+	return (duration >= limit*(1-band)) && (duration <= limit*(1+band))
+}
+
+// Re-run only trusted for timing if it ended same way as first run
+func isSameRunOutcome(err, rErr error, stdout, rStdout string) bool {
+	if (err == nil) != (rErr == nil) {
+		return false
+	}
+	if (err != nil) && (err.Error() != rErr.Error()) {
+		return false
+	}
+	return stdout == rStdout
+}
+
 func (grt *GargoyleRpcTask) ProcessSubmission(req RpcSubmissionRequest, resp *RpcSubmissionResponse) error {
 	log := gylib.GetStdLog()
 	sub := req.Submission
@@ -55,14 +116,26 @@ func (grt *GargoyleRpcTask) ProcessSubmission(req RpcSubmissionRequest, resp *Rp
 		return err
 	}
 	defer parent.taskHandler.SlaveFinishProcess(workDir)
+	grt.runningMutex.Lock()
+	grt.running[sub.Id] = &rpcRunningSubmission{workDir: workDir}
+	grt.runningMutex.Unlock()
+	defer func() {
+		grt.runningMutex.Lock()
+		delete(grt.running, sub.Id)
+		grt.runningMutex.Unlock()
+	}()
 	log.Printf("[subId:%d] saved in temporary dir %s", sub.Id, workDir)
 	compileArgs := strings.Split(lang.CompileCommand, " ")
 	for k, v := range compileArgs {
-		parsed := parent.ParseVars(sub, lang, workDir, v)
+		parsed := parent.ParseVars(sub, lang, prob, workDir, v)
 		compileArgs[k] = parsed
 	}
 	log.Printf("[subId:%d] Executing compilation with args: %v", sub.Id, compileArgs)
 	compileDuration, stdout, stderr, err := parent.taskHandler.SlaveCompileCode(compileArgs, workDir)
+	if grt.isCancelled(sub.Id) {
+		log.Printf("[subId:%d] Cancelled while compiling", sub.Id)
+		return ErrSubmissionCancelled
+	}
 	sub.CompileStdout = stdout
 	sub.CompileStderr = stderr
 	if err != nil {
@@ -81,14 +154,37 @@ func (grt *GargoyleRpcTask) ProcessSubmission(req RpcSubmissionRequest, resp *Rp
 		testPassed := 0
 		runArgs := strings.Split(lang.ExecuteCommand, " ")
 		for k, v := range runArgs {
-			parsed := parent.ParseVars(sub, lang, workDir, v)
+			parsed := parent.ParseVars(sub, lang, prob, workDir, v)
 			runArgs[k] = parsed
 		}
+		// Effective limits after language adjustment
+		timeLimit := lang.GetTimeLimit(prob.TimeLimit)
+		memLimit := lang.GetMemLimit(prob.MemLimit)
+		timeout := timeLimit * 2
+		log.Printf("[subId:%d] Effective limits: %dms, %dMB", sub.Id, timeLimit, memLimit)
 		var testResult []gytypes.TestResultData
 		for _, testCase := range testCases {
+			if grt.isCancelled(sub.Id) {
+				log.Printf("[subId:%d] Cancelled before testcase %d", sub.Id, testCase.TestNo)
+				return ErrSubmissionCancelled
+			}
 			log.Printf("[subId:%d] Executing testcase %d with args: %v", sub.Id, testCase.TestNo, runArgs)
 			// For obvious reason, timeout are n*2 from defined
-			duration, memory, stdout, _, err := parent.taskHandler.SlaveRunCode(runArgs, workDir, testCase.Input, prob.TimeLimit*2)
+			duration, memory, stdout, _, err := parent.taskHandler.SlaveRunCode(runArgs, workDir, testCase.Input, timeout)
+			samples := []float64{duration}
+			// Timing noise near the limit, re-run and use the fastest time, output of first run still judged
+			for len(samples) < req.TimingRerun && grt.isBorderlineTime(duration, timeLimit, req.TimingBand) {
+				log.Printf("[subId:%d] Testcase %d took %fms near %dms limit, re-run #%d", sub.Id, testCase.TestNo, duration, timeLimit, len(samples))
+				rDuration, _, rStdout, _, rErr := parent.taskHandler.SlaveRunCode(runArgs, workDir, testCase.Input, timeout)
+				samples = append(samples, rDuration)
+				if !isSameRunOutcome(err, rErr, stdout, rStdout) {
+					log.Printf("[subId:%d] Testcase %d re-run #%d ended differently, time not used", sub.Id, testCase.TestNo, len(samples)-1)
+					continue
+				}
+				if rDuration < duration {
+					duration = rDuration
+				}
+			}
 			result := gytypes.TestResultData{
 				ProblemId:    sub.ProblemId,
 				SubmissionId: sub.Id,
@@ -97,21 +193,22 @@ func (grt *GargoyleRpcTask) ProcessSubmission(req RpcSubmissionRequest, resp *Rp
 				TimeElapsed:  duration,
 				MemoryUsed:   memory,
 				Score:        0,
+				TimeSamples:  samples,
 			}
 			// Seems runtime error occurs, we will investigate for
 			if err != nil {
-				if duration > 1000 {
+				if duration > float64(timeLimit) {
 					result.Verdict = gytypes.SubmissionTimeLimitExceeded
-				} else if lang.LimitMemory && (memory > uint64(prob.MemLimit*1024*1024)) {
+				} else if lang.LimitMemory && (memory > uint64(memLimit*1024*1024)) {
 					result.Verdict = gytypes.SubmissionMemoryLimitExceeded
 				} else {
 					result.Verdict = gytypes.SubmissionRuntimeError
 				}
 			} else {
 				// Not returning error, but we will check for time and memory constrains
-				if duration > float64(prob.TimeLimit*1000) {
+				if duration > float64(timeLimit) {
 					result.Verdict = gytypes.SubmissionTimeLimitExceeded
-				} else if lang.LimitMemory && (memory > uint64(prob.MemLimit*1024*1024)) {
+				} else if lang.LimitMemory && (memory > uint64(memLimit*1024*1024)) {
 					result.Verdict = gytypes.SubmissionMemoryLimitExceeded
 				} else {
 					// Now compare for stdout
@@ -164,6 +261,10 @@ func (grt *GargoyleRpcTask) ProcessSubmission(req RpcSubmissionRequest, resp *Rp
 		sub.Verdict = gytypes.SubmissionAccepted
 		sub.Score = 100
 	}
+	// Last test case may be killed by cancellation
+	if grt.isCancelled(sub.Id) {
+		return ErrSubmissionCancelled
+	}
 	resp.Submission = sub
 	log.Printf("[subId:%d] Graded with verdict:%s and score %d", sub.Id, sub.Verdict, sub.Score)
 	return nil
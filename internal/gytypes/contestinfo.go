@@ -10,6 +10,7 @@ package gytypes
 
 import (
 	"html/template"
+	"strconv"
 	"time"
 )
 
@@ -35,17 +36,18 @@ type ContestData struct {
 }
 
 type ProblemData struct {
-	Id          int
-	ContestId   int
-	Name        string
-	ShortName   string
-	Description template.HTML
-	TimeLimit   int
-	MemLimit    int
-	MaxAttempts int
-	ProblemUrl  string
-	ContestUrl  string
-	AllowedLang string
+	Id           int
+	ContestId    int
+	Name         string
+	ShortName    string
+	Description  template.HTML
+	TimeLimit    int // in milliseconds
+	MemLimit     int // in MB
+	MaxAttempts  int
+	ProblemUrl   string
+	ContestUrl   string
+	AllowedLang  string
+	TimeLimitStr string // Used for written in page
 }
 
 type ContestAccess struct {
@@ -62,3 +64,8 @@ type ProblemSet struct {
 	Contest  ContestData
 	Problems []ProblemData
 }
+
+// Format time limit in seconds with fraction if needed, e.g 1500ms as "1.5"
+func FormatTimeLimit(timeLimit int) string {
+	return strconv.FormatFloat(float64(timeLimit)/1000, 'f', -1, 64)
+}
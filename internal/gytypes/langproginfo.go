@@ -24,6 +24,23 @@ type LanguageProgramData struct {
 	RegexReplaceFrom string
 	RegexReplaceTo   string
 	ForbiddenKeys    string
+	TimeMultiplier   float64 // Multiplier applied to problem time limit
+	TimeOffset       int     // Additional time in milliseconds after multiplied
+	MemOverhead      int     // Additional memory allowance in MB (e.g for JVM)
 }
 
 type LanguageProgramMap map[int]LanguageProgramData
+
+// Get effective time limit in milliseconds for this language, from problem time limit in milliseconds
+func (lp *LanguageProgramData) GetTimeLimit(problemTimeLimit int) int {
+	multiplier := lp.TimeMultiplier
+	if multiplier <= 0 {
+		multiplier = 1
+	}
+	return int(float64(problemTimeLimit)*multiplier) + lp.TimeOffset
+}
+
+// Get effective memory limit in MB for this language, from problem memory limit in MB
+func (lp *LanguageProgramData) GetMemLimit(problemMemLimit int) int {
+	return problemMemLimit + lp.MemOverhead
+}
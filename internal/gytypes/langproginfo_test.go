@@ -0,0 +1,63 @@
+package gytypes
+
+/* GargoyleJudge - Simple Judgement System for Competitive Programming
+ * Copyright (C) Thiekus 2019
+ * Visit www.khayalan.id for updates
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/. */
+
+import (
+	"testing"
+)
+
+func TestGetTimeLimit(t *testing.T) {
+	tests := []struct {
+		name       string
+		multiplier float64
+		offset     int
+		problem    int
+		want       int
+	}{
+		{"plain", 1, 0, 1000, 1000},
+		{"sub second", 1, 0, 500, 500},
+		{"unset multiplier", 0, 0, 2000, 2000},
+		{"negative multiplier", -1, 0, 2000, 2000},
+		{"java defaults", 2, 1000, 1000, 3000},
+		{"fractional multiplier", 1.5, 0, 1500, 2250},
+		{"offset only", 1, 250, 750, 1000},
+		{"truncated", 1.3, 0, 1001, 1301},
+	}
+	for _, tt := range tests {
+		lp := LanguageProgramData{TimeMultiplier: tt.multiplier, TimeOffset: tt.offset}
+		if got := lp.GetTimeLimit(tt.problem); got != tt.want {
+			t.Errorf("%s: GetTimeLimit(%d) = %d, want %d", tt.name, tt.problem, got, tt.want)
+		}
+	}
+}
+
+func TestGetMemLimit(t *testing.T) {
+	lp := LanguageProgramData{MemOverhead: 64}
+	if got := lp.GetMemLimit(256); got != 320 {
+		t.Errorf("GetMemLimit(256) = %d, want 320", got)
+	}
+}
+
+func TestFormatTimeLimit(t *testing.T) {
+	tests := []struct {
+		timeLimit int
+		want      string
+	}{
+		{1000, "1"},
+		{1500, "1.5"},
+		{250, "0.25"},
+		{2001, "2.001"},
+		{0, "0"},
+	}
+	for _, tt := range tests {
+		if got := FormatTimeLimit(tt.timeLimit); got != tt.want {
+			t.Errorf("FormatTimeLimit(%d) = %q, want %q", tt.timeLimit, got, tt.want)
+		}
+	}
+}
@@ -19,6 +19,7 @@ type ScoreboardData struct {
 	ContestantCount int
 	Contestant      ScoreContestantDataList
 	Problems        []ScoreContestProblemData
+	ShowPenalty     bool
 	LastUpdate      time.Time
 }
 
@@ -32,6 +33,7 @@ type ScoreContestInfo struct {
 	AllowPublic    bool
 	StartTimestamp time.Time
 	PenaltyTime    int64
+	Unfrozen       bool // Public scoreboard already synced with private one
 }
 
 type ScoreContestProblemData struct {
@@ -40,6 +42,10 @@ type ScoreContestProblemData struct {
 	Name        string
 	ShortName   string
 	CircleColor string
+	// First to solve this problem on whole contest, zero if no one yet
+	FirstSolverId     int
+	FirstSolverName   string
+	FirstSolveTimeStr string
 }
 
 type ScoreContestantData struct {
@@ -51,6 +57,9 @@ type ScoreContestantData struct {
 	RankNumber       int
 	TotalScore       int
 	TotalPenaltyTime int64
+	SolvedCount      int   // Problems with full score
+	AttemptCount     int   // Counted submissions on all problems
+	LastScoreTime    int64 // Elapsed time when total score reached, used on IOI
 	Problems         []ScoreProblemData
 	PenaltyTimeStr   string
 	StartTime        int64
@@ -67,10 +76,46 @@ type ScoreProblemData struct {
 	AcceptedTime    int64
 	PenaltyTime     int64
 	SubmissionCount int
-	OneHit          bool
+	OneHit          bool // First to solve on whole contest
 	Regraded        bool
 	AcceptedTimeStr string
 	IsAccepted      bool
+	GroupFirst      bool   // First to solve among contestants of same group, derived on render only
+	CellText        string // Shown on scoreboard, as contest style rules
+}
+
+// Judged submission used to compute score of a problem
+type ScoreSubmissionData struct {
+	SubmissionId int
+	ProblemId    int
+	UserId       int
+	Verdict      string
+	Score        int
+	SubmitTime   int64
+	Regraded     bool
+}
+
+// Kind of changes done while rebuilding scoreboard
+const (
+	ScoreRebuildInsert = "insert"
+	ScoreRebuildUpdate = "update"
+	ScoreRebuildDelete = "delete"
+)
+
+// Difference between stored score and one recomputed from submissions
+type ScoreRebuildDiff struct {
+	Action      string
+	Public      bool
+	UserName    string
+	ProblemName string
+	Current     ScoreProblemData
+	Rebuilt     ScoreProblemData
+}
+
+type ScoreRebuildPlan struct {
+	ContestId   int
+	ContestName string
+	Diffs       []ScoreRebuildDiff
 }
 
 type ScoreboardListData struct {
@@ -83,8 +128,20 @@ type ScoreboardListData struct {
 }
 
 const (
-	ScoreStyleICPC = "ICPC"
-	ScoreStyleIOI  = "IOI"
+	ScoreStyleICPC       = "ICPC"
+	ScoreStyleIOI        = "IOI"
+	ScoreStyleAtCoder    = "AtCoder"    // Score, then last improvement time
+	ScoreStyleCodeforces = "Codeforces" // Points decayed by time
+)
+
+// Full score of a problem
+const ScoreFullPoints = 100
+
+// Tie-breakers of contestants with same total score on IOI, applied in configured order
+const (
+	ScoreTieBreakSolved   = "solved"   // More fully solved problems first
+	ScoreTieBreakTime     = "time"     // Reached total score earlier first
+	ScoreTieBreakAttempts = "attempts" // Fewer submissions first
 )
 
 func (cd ScoreContestantDataList) Len() int {
@@ -95,9 +152,9 @@ func (cd ScoreContestantDataList) Swap(i, j int) {
 	cd[i], cd[j] = cd[j], cd[i]
 }
 
+// Sorted as ICPC, other styles ranked by their own rules
 func (cd ScoreContestantDataList) Less(i, j int) bool {
 	// Sort from biggest point to lesser point
-	// TODO: implements scoring sort other than ICPC
 	if cd[i].TotalScore == cd[j].TotalScore {
 		// Bigger penalty, then lower rank for same score
 		return cd[i].TotalPenaltyTime < cd[j].TotalPenaltyTime
@@ -9,9 +9,32 @@ package gytypes
  * file, You can obtain one at https://mozilla.org/MPL/2.0/. */
 
 type SlaveData struct {
-	Id      int
-	Name    string
-	Address string
-	Enable  bool
-	Active  bool
+	Id        int
+	Name      string
+	Address   string
+	Enable    bool
+	Active    bool
+	Embedded  bool    // Judge worker running inside master process
+	Weight    int     // Bigger weight receives more jobs
+	Capacity  int     // Maximum concurrent jobs, 0 for unlimited
+	Approved  bool    // Self-registered slave must be approved by admin
+	Heartbeat int64   // Last heartbeat from self-registered slave
+	InFlight  int     // Jobs currently judged by this slave
+	Latency   float64 // Last ping delta in ms
+	OSName    string
+	Arch      string
+	NumCPU    int
+}
+
+// Load relative to weight, lower is preferred for next job
+func (sd *SlaveData) GetLoad() float64 {
+	weight := sd.Weight
+	if weight <= 0 {
+		weight = 1
+	}
+	return float64(sd.InFlight) / float64(weight)
+}
+
+func (sd *SlaveData) HasCapacity() bool {
+	return (sd.Capacity <= 0) || (sd.InFlight < sd.Capacity)
 }
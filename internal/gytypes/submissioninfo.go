@@ -25,6 +25,8 @@ type SubmissionData struct {
 	CompileTime   float64
 	CompileStdout string
 	CompileStderr string
+	JudgeState    string
+	Regraded      bool
 	// retrieved from another tables
 	UserDisplayName string
 	ProblemName     string
@@ -33,6 +35,51 @@ type SubmissionData struct {
 	LanguageSyntax  string
 }
 
+type JudgeQueueData struct {
+	SubmissionId int
+	ProblemId    int
+	UserId       int
+	LanguageId   int
+	SubmitTime   time.Time
+	JudgeState   string
+	Priority     int
+}
+
+// Different results of same submission judged on two slaves
+type JudgeMismatchData struct {
+	Id           int
+	SubmissionId int
+	SlaveA       string
+	VerdictA     string
+	ScoreA       int
+	ResultA      string // Serialized slave response
+	SlaveB       string
+	VerdictB     string
+	ScoreB       int
+	ResultB      string
+	CreateTime   time.Time
+	Resolution   string // Empty if not yet resolved
+	ResolvedBy   int
+	ResolveTime  time.Time
+	// retrieved from another tables
+	UserDisplayName string
+	ProblemName     string
+}
+
+// Criteria of submissions to be rejudged, zero or empty field means any
+type RejudgeFilter struct {
+	SubmissionId int
+	ProblemId    int
+	ContestId    int
+	LanguageId   int
+	Verdict      string
+}
+
+func (rf *RejudgeFilter) IsEmpty() bool {
+	return (rf.SubmissionId == 0) && (rf.ProblemId == 0) && (rf.ContestId == 0) && (rf.LanguageId == 0) &&
+		(rf.Verdict == "")
+}
+
 type TestCaseData struct {
 	Id        int
 	ProblemId int
@@ -50,6 +97,7 @@ type TestResultData struct {
 	TimeElapsed  float64
 	MemoryUsed   uint64
 	Score        float64
+	TimeSamples  []float64 // All measured run times, more than one if re-run on borderline
 }
 
 // Based from ACM-ICPC rules guide
@@ -67,8 +115,45 @@ const (
 	SubmissionError               = "SE"
 	SubmissionRestrictedFunction  = "RF"
 	SubmissionCantJudged          = "CJ"
+	SubmissionCancelled           = "CA"
+)
+
+// Judging queue state of submission, independent from verdict
+const (
+	JudgeStateQueued    = "queued"
+	JudgeStateJudging   = "judging"
+	JudgeStateDone      = "done"
+	JudgeStateFailed    = "failed"
+	JudgeStateCancelled = "cancelled"
+	JudgeStateHeld      = "held" // Slaves disagree, waiting for jury review
+)
+
+// Jury decision on mismatched double judging
+const (
+	MismatchAcceptA = "a"
+	MismatchAcceptB = "b"
+	MismatchRejudge = "rejudge"
 )
 
+// Judging priority class, higher one dispatched first
+const (
+	JudgePriorityLow    = 0 // Jury test runs and rejudges
+	JudgePriorityNormal = 1 // Practice submissions
+	JudgePriorityHigh   = 2 // Running contest submissions
+)
+
+// Verdicts not counted as attempt on problem, judging system failure and cancelled by jury
+var UncountedAttemptVerdicts = []string{SubmissionError, SubmissionCancelled}
+
+func IsCountedAttempt(verdict string) bool {
+	for _, v := range UncountedAttemptVerdicts {
+		if verdict == v {
+			return false
+		}
+	}
+	return true
+}
+
 func (si *SubmissionData) GetStatusMessage() string {
 	return TranslateSubmissionCode(si.Verdict)
 }
@@ -101,6 +186,8 @@ func TranslateSubmissionCode(code string) string {
 		return "Restricted Function"
 	case SubmissionCantJudged:
 		return "Can't be Judged"
+	case SubmissionCancelled:
+		return "Cancelled"
 	}
 	return SubmissionError
 }
@@ -0,0 +1,34 @@
+package gytypes
+
+/* GargoyleJudge - Simple Judgement System for Competitive Programming
+ * Copyright (C) Thiekus 2019
+ * Visit www.khayalan.id for updates
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/. */
+
+import (
+	"testing"
+)
+
+func TestIsCountedAttempt(t *testing.T) {
+	tests := []struct {
+		verdict string
+		want    bool
+	}{
+		{SubmissionAccepted, true},
+		{SubmissionWrongAnswer, true},
+		{SubmissionCompilerError, true},
+		{SubmissionTimeLimitExceeded, true},
+		{SubmissionRuntimeError, true},
+		{SubmissionOnQueue, true},
+		{SubmissionError, false},
+		{SubmissionCancelled, false},
+	}
+	for _, tt := range tests {
+		if got := IsCountedAttempt(tt.verdict); got != tt.want {
+			t.Errorf("IsCountedAttempt(%q) = %v, want %v", tt.verdict, got, tt.want)
+		}
+	}
+}
@@ -12,6 +12,13 @@ var sidebarHovered = false;
 // TickTock
 var ttRemainTime = 0
 
+// Live events stream, handlers registered by current page subview
+var liveEventSource = null;
+var liveEventContestId = 0;
+var liveScoreboardContestId = 0;
+var liveScoreboardHandler = null;
+var liveSubmissionHandler = null;
+
 // Roll down dashboard message while showing
 function animateDashboardMessage() {
     $("#gyDashboardMessage").delay(250).slideDown(500);
@@ -153,11 +160,13 @@ function progressiveDashboardPageLoadSuccess(resp, status, xhr, pushPage) {
         hideDashboardLoading();
         $("#gyDashboardContent").fadeIn(200, function () {
             // After transplated
+            resetLiveEventHandlers();
             try {
                 subviewInit();
             } catch (err) {
                 alert(err);
             }
+            connectLiveEvents(liveScoreboardContestId);
             redefineAnchorVisit();
             animateDashboardMessage();
         });
@@ -205,6 +214,53 @@ function progressiveDashboardPagePost(action, formData) {
     }
 }
 
+// Open live events stream, reopened only when watched scoreboard changed
+function connectLiveEvents(contestId) {
+    if (!window.EventSource) {
+        return;
+    }
+    if ((liveEventSource !== null) && (liveEventContestId === contestId)) {
+        return;
+    }
+    if (liveEventSource !== null) {
+        liveEventSource.close();
+    }
+    var url = getBaseUrl() + "/dashboard/liveEvents";
+    if (contestId > 0) {
+        url += "?contest=" + contestId;
+    }
+    liveEventContestId = contestId;
+    liveEventSource = new EventSource(url);
+    liveEventSource.addEventListener("scoreboard", function (event) {
+        var data = JSON.parse(event.data);
+        if ((liveScoreboardHandler !== null) && (data.contestId === liveScoreboardContestId)) {
+            liveScoreboardHandler(data);
+        }
+    });
+    liveEventSource.addEventListener("submission", function (event) {
+        if (liveSubmissionHandler !== null) {
+            liveSubmissionHandler(JSON.parse(event.data));
+        }
+    });
+}
+
+// Called by subviewInit to receive scoreboard changes of contest
+function subscribeLiveScoreboard(contestId, handler) {
+    liveScoreboardContestId = contestId;
+    liveScoreboardHandler = handler;
+}
+
+// Called by subviewInit to receive verdict changes of own submissions
+function subscribeLiveSubmissions(handler) {
+    liveSubmissionHandler = handler;
+}
+
+function resetLiveEventHandlers() {
+    liveScoreboardContestId = 0;
+    liveScoreboardHandler = null;
+    liveSubmissionHandler = null;
+}
+
 function redefineAnchorVisit() {
     $("a").each(function () {
         var anchor = $(this);
@@ -369,6 +425,7 @@ $(document).ready(function () {
     }, 3000);
     // Page-defined custom initialization
     subviewInit();
+    connectLiveEvents(liveScoreboardContestId);
     redefineAnchorVisit();
     refreshAjaxNotifications();
     animateDashboardMessage();
@@ -1,6 +1,13 @@
 -- Gargoyle Judgement System main database schema
 -- Copyright (C) Thiekus 2019
 
+-- Applied migrations, recreated and marked after this schema created
+-- {{if eq .Driver "sqlserver"}}
+IF OBJECT_ID('{{.TablePrefix}}migrations', 'U') IS NOT NULL DROP TABLE {{.TablePrefix}}migrations;
+-- {{else}}
+DROP TABLE IF EXISTS {{.TablePrefix}}migrations;
+-- {{end}}
+
 -- Programming Languages
 -- {{if eq .Driver "sqlserver"}}
 IF OBJECT_ID('{{.TablePrefix}}languages', 'U') IS NOT NULL DROP TABLE {{.TablePrefix}}languages;
@@ -22,24 +29,27 @@ CREATE TABLE {{.TablePrefix}}languages (
     limit_syscall INTEGER NOT NULL DEFAULT 0,
     preg_replace_from VARCHAR(200) NOT NULL DEFAULT '',
     preg_replace_to VARCHAR(200) NOT NULL DEFAULT '',
-    forbidden_keys TEXT NOT NULL
+    forbidden_keys TEXT NOT NULL,
+    time_multiplier REAL NOT NULL DEFAULT 1,
+    time_offset INTEGER NOT NULL DEFAULT 0,
+    mem_overhead INTEGER NOT NULL DEFAULT 0
 );
 -- Default languages
 -- Pure C using GCC
-INSERT INTO {{.TablePrefix}}languages (ext_name, display_name, enabled, syntax_name, source_name, exe_name, compile_cmd, exec_cmd, enable_sandbox, limit_memory, limit_syscall, preg_replace_from, preg_replace_to, forbidden_keys)
-    VALUES ('c', 'C', 1, 'c_cpp', 'appmain.c', 'appmain', 'gcc -Wall -o \{\{.WorkPath\}\}/\{\{.ExeName\}\} -O2 -std=gnu99 -lm \{\{.WorkPath\}\}/\{\{.SourceName\}\}', '\{\{.WorkPath\}\}/\{\{.ExeName\}\}', 1, 1, 1, '', '', '');
+INSERT INTO {{.TablePrefix}}languages (ext_name, display_name, enabled, syntax_name, source_name, exe_name, compile_cmd, exec_cmd, enable_sandbox, limit_memory, limit_syscall, preg_replace_from, preg_replace_to, forbidden_keys, time_multiplier, time_offset, mem_overhead)
+    VALUES ('c', 'C', 1, 'c_cpp', 'appmain.c', 'appmain', 'gcc -Wall -o \{\{.WorkPath\}\}/\{\{.ExeName\}\} -O2 -std=gnu99 -lm \{\{.WorkPath\}\}/\{\{.SourceName\}\}', '\{\{.WorkPath\}\}/\{\{.ExeName\}\}', 1, 1, 1, '', '', '', 1, 0, 0);
 -- C++ using GCC
-INSERT INTO {{.TablePrefix}}languages (ext_name, display_name, enabled, syntax_name, source_name, exe_name, compile_cmd, exec_cmd, enable_sandbox, limit_memory, limit_syscall, preg_replace_from, preg_replace_to, forbidden_keys)
-    VALUES ('cpp', 'C++', 1, 'c_cpp', 'appmain.cpp', 'appmain', 'g++ -Wall -o \{\{.WorkPath\}\}/\{\{.ExeName\}\} -O2 -std=gnu++14 -lm \{\{.WorkPath\}\}/\{\{.SourceName\}\}', '\{\{.WorkPath\}\}/\{\{.ExeName\}\}', 1, 1, 1, '', '', '');
+INSERT INTO {{.TablePrefix}}languages (ext_name, display_name, enabled, syntax_name, source_name, exe_name, compile_cmd, exec_cmd, enable_sandbox, limit_memory, limit_syscall, preg_replace_from, preg_replace_to, forbidden_keys, time_multiplier, time_offset, mem_overhead)
+    VALUES ('cpp', 'C++', 1, 'c_cpp', 'appmain.cpp', 'appmain', 'g++ -Wall -o \{\{.WorkPath\}\}/\{\{.ExeName\}\} -O2 -std=gnu++14 -lm \{\{.WorkPath\}\}/\{\{.SourceName\}\}', '\{\{.WorkPath\}\}/\{\{.ExeName\}\}', 1, 1, 1, '', '', '', 1, 0, 0);
 -- Pascal using FPC
-INSERT INTO {{.TablePrefix}}languages (ext_name, display_name, enabled, syntax_name, source_name, exe_name, compile_cmd, exec_cmd, enable_sandbox, limit_memory, limit_syscall, preg_replace_from, preg_replace_to, forbidden_keys)
-    VALUES ('pas', 'Pascal', 1, 'pascal', 'appmain.pas', 'appmain', 'fpc -O2 -XS -Sg \{\{.WorkPath\}\}/\{\{.SourceName\}\}', '\{\{.WorkPath\}\}/\{\{.ExeName\}\}', 1, 1, 1, '', '', '');
+INSERT INTO {{.TablePrefix}}languages (ext_name, display_name, enabled, syntax_name, source_name, exe_name, compile_cmd, exec_cmd, enable_sandbox, limit_memory, limit_syscall, preg_replace_from, preg_replace_to, forbidden_keys, time_multiplier, time_offset, mem_overhead)
+    VALUES ('pas', 'Pascal', 1, 'pascal', 'appmain.pas', 'appmain', 'fpc -O2 -XS -Sg \{\{.WorkPath\}\}/\{\{.SourceName\}\}', '\{\{.WorkPath\}\}/\{\{.ExeName\}\}', 1, 1, 1, '', '', '', 1, 0, 0);
 -- Java
-INSERT INTO {{.TablePrefix}}languages (ext_name, display_name, enabled, syntax_name, source_name, exe_name, compile_cmd, exec_cmd, enable_sandbox, limit_memory, limit_syscall, preg_replace_from, preg_replace_to, forbidden_keys)
-    VALUES ('java', 'Java', 1, 'java', 'PandoraApp.java', 'PandoraApp.class', 'javac -encoding UTF-8 -d . \{\{.WorkPath\}\}/\{\{.SourceName\}\}', 'java -Xmx\{\{.MemLimit\}\}m -cp \{\{.WorkPath\}\} PandoraApp', 0, 0, 1, 'class .*\\{|class .*\\s{', 'class PandoraApp {', '');
+INSERT INTO {{.TablePrefix}}languages (ext_name, display_name, enabled, syntax_name, source_name, exe_name, compile_cmd, exec_cmd, enable_sandbox, limit_memory, limit_syscall, preg_replace_from, preg_replace_to, forbidden_keys, time_multiplier, time_offset, mem_overhead)
+    VALUES ('java', 'Java', 1, 'java', 'PandoraApp.java', 'PandoraApp.class', 'javac -encoding UTF-8 -d . \{\{.WorkPath\}\}/\{\{.SourceName\}\}', 'java -Xmx\{\{.MemLimit\}\}m -cp \{\{.WorkPath\}\} PandoraApp', 0, 0, 1, 'class .*\\{|class .*\\s{', 'class PandoraApp {', '', 2, 1000, 0);
 -- Golang
-INSERT INTO {{.TablePrefix}}languages (ext_name, display_name, enabled, syntax_name, source_name, exe_name, compile_cmd, exec_cmd, enable_sandbox, limit_memory, limit_syscall, preg_replace_from, preg_replace_to, forbidden_keys)
-    VALUES ('go', 'Go', 1, 'golang', 'appmain.go', 'appmain', 'go build -o \{\{.WorkPath\}\}/\{\{.ExeName\}\} \{\{.WorkPath\}\}/', '\{\{.WorkPath\}\}/\{\{.ExeName\}\}', 1, 1, 1, '', '', '');
+INSERT INTO {{.TablePrefix}}languages (ext_name, display_name, enabled, syntax_name, source_name, exe_name, compile_cmd, exec_cmd, enable_sandbox, limit_memory, limit_syscall, preg_replace_from, preg_replace_to, forbidden_keys, time_multiplier, time_offset, mem_overhead)
+    VALUES ('go', 'Go', 1, 'golang', 'appmain.go', 'appmain', 'go build -o \{\{.WorkPath\}\}/\{\{.ExeName\}\} \{\{.WorkPath\}\}/', '\{\{.WorkPath\}\}/\{\{.ExeName\}\}', 1, 1, 1, '', '', '', 1, 0, 0);
 
 -- User table
 -- {{if eq .Driver "sqlserver"}}
@@ -147,7 +157,8 @@ CREATE TABLE {{.TablePrefix}}contests (
     freeze_timestamp INTEGER NOT NULL DEFAULT 0,
     unfreeze_timestamp INTEGER NOT NULL DEFAULT 0,
     max_runtime INTEGER NOT NULL DEFAULT 0,
-    penalty_time INTEGER NOT NULL DEFAULT 0
+    penalty_time INTEGER NOT NULL DEFAULT 0,
+    unfrozen INTEGER NOT NULL DEFAULT 0
 );
 
 -- Problem List
@@ -162,7 +173,7 @@ CREATE TABLE {{.TablePrefix}}problems (
     problem_name VARCHAR(50) NOT NULL DEFAULT 'Untitled Problem',
     problem_shortname VARCHAR(20) NOT NULL DEFAULT 'Untitled',
     description TEXT NOT NULL,
-    time_limit INTEGER NOT NULL DEFAULT 1,
+    time_limit INTEGER NOT NULL DEFAULT 1000,
     mem_limit INTEGER NOT NULL DEFAULT 32,
     max_attempts INTEGER NOT NULL DEFAULT 0
 );
@@ -200,7 +211,13 @@ CREATE TABLE {{.TablePrefix}}submissions (
     submit_time INTEGER NOT NULL DEFAULT 0,
     compile_time REAL NOT NULL DEFAULT 0,
     compile_stdout TEXT NOT NULL,
-    compile_stderr TEXT NOT NULL
+    compile_stderr TEXT NOT NULL,
+    judge_state VARCHAR(10) NOT NULL DEFAULT 'queued',
+    lease_time INTEGER NOT NULL DEFAULT 0,
+    judged_by VARCHAR(200) NOT NULL DEFAULT '',
+    judged_time INTEGER NOT NULL DEFAULT 0,
+    regraded INTEGER NOT NULL DEFAULT 0,
+    judge_priority INTEGER NOT NULL DEFAULT 1
 );
 
 -- Contest problem testcase
@@ -231,7 +248,8 @@ CREATE TABLE {{.TablePrefix}}testresults (
     verdict VARCHAR(4) NOT NULL DEFAULT 'QU',
     time_elapsed REAL NOT NULL DEFAULT 0,
     memory_used INTEGER NOT NULL DEFAULT 0,
-    score REAL NOT NULL DEFAULT 0
+    score REAL NOT NULL DEFAULT 0,
+    time_samples VARCHAR(200) NOT NULL DEFAULT ''
 );
 
 -- Contest score for internal beholder (Admin and Jury)
@@ -272,6 +290,21 @@ CREATE TABLE {{.TablePrefix}}scores_public (
     PRIMARY KEY (id_contest, id_problem, id_user)
 );
 
+-- Frozen scores revealed on public scoreboard by resolver
+-- {{if eq .Driver "sqlserver"}}
+IF OBJECT_ID('{{.TablePrefix}}score_reveals', 'U') IS NOT NULL DROP TABLE {{.TablePrefix}}score_reveals;
+-- {{else}}
+DROP TABLE IF EXISTS {{.TablePrefix}}score_reveals;
+-- {{end}}
+CREATE TABLE {{.TablePrefix}}score_reveals (
+    id_contest INTEGER NOT NULL,
+    id_problem INTEGER NOT NULL,
+    id_user INTEGER NOT NULL,
+    reveal_time INTEGER NOT NULL DEFAULT 0,
+    revealed_by INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY (id_contest, id_problem, id_user)
+);
+
 -- Slaves list
 -- {{if eq .Driver "sqlserver"}}
 IF OBJECT_ID('{{.TablePrefix}}slaves', 'U') IS NOT NULL DROP TABLE {{.TablePrefix}}slaves;
@@ -282,12 +315,53 @@ CREATE TABLE {{.TablePrefix}}slaves (
     id INTEGER PRIMARY KEY {{.AutoIncrement}},
     name VARCHAR(200) NOT NULL DEFAULT 'Unnamed',
     address VARCHAR(200) NOT NULL,
-    enable INTEGER NOT NULL DEFAULT 1
+    enable INTEGER NOT NULL DEFAULT 1,
+    weight INTEGER NOT NULL DEFAULT 1,
+    capacity INTEGER NOT NULL DEFAULT 2,
+    approved INTEGER NOT NULL DEFAULT 1,
+    heartbeat_time INTEGER NOT NULL DEFAULT 0
 );
 -- Insert default slave
 INSERT INTO {{.TablePrefix}}slaves (name, address, enable)
     VALUES ('Localhost Slave', 'localhost:28499', 1);
 
+-- Verdict mismatches found by double judging, waiting for jury review
+-- {{if eq .Driver "sqlserver"}}
+IF OBJECT_ID('{{.TablePrefix}}judge_mismatches', 'U') IS NOT NULL DROP TABLE {{.TablePrefix}}judge_mismatches;
+-- {{else}}
+DROP TABLE IF EXISTS {{.TablePrefix}}judge_mismatches;
+-- {{end}}
+CREATE TABLE {{.TablePrefix}}judge_mismatches (
+    id INTEGER PRIMARY KEY {{.AutoIncrement}},
+    id_submission INTEGER NOT NULL,
+    slave_a VARCHAR(200) NOT NULL,
+    verdict_a VARCHAR(4) NOT NULL,
+    score_a INTEGER NOT NULL DEFAULT 0,
+    result_a TEXT NOT NULL,
+    slave_b VARCHAR(200) NOT NULL,
+    verdict_b VARCHAR(4) NOT NULL,
+    score_b INTEGER NOT NULL DEFAULT 0,
+    result_b TEXT NOT NULL,
+    create_time INTEGER NOT NULL DEFAULT 0,
+    resolution VARCHAR(10) NOT NULL DEFAULT '',
+    resolved_by INTEGER NOT NULL DEFAULT 0,
+    resolve_time INTEGER NOT NULL DEFAULT 0
+);
+
+-- Actions done by jury, admin or system (id_user 0)
+-- {{if eq .Driver "sqlserver"}}
+IF OBJECT_ID('{{.TablePrefix}}audit_logs', 'U') IS NOT NULL DROP TABLE {{.TablePrefix}}audit_logs;
+-- {{else}}
+DROP TABLE IF EXISTS {{.TablePrefix}}audit_logs;
+-- {{end}}
+CREATE TABLE {{.TablePrefix}}audit_logs (
+    id INTEGER PRIMARY KEY {{.AutoIncrement}},
+    id_user INTEGER NOT NULL DEFAULT 0,
+    action VARCHAR(50) NOT NULL,
+    details VARCHAR(200) NOT NULL DEFAULT '',
+    create_time INTEGER NOT NULL DEFAULT 0
+);
+
 -- Notifications
 -- {{if eq .Driver "sqlserver"}}
 IF OBJECT_ID('{{.TablePrefix}}notifications', 'U') IS NOT NULL DROP TABLE {{.TablePrefix}}notifications;
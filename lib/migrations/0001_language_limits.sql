@@ -0,0 +1,10 @@
+-- Per-language time multiplier, time offset and memory overhead
+ALTER TABLE {{.TablePrefix}}languages ADD time_multiplier REAL NOT NULL DEFAULT 1;
+ALTER TABLE {{.TablePrefix}}languages ADD time_offset INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE {{.TablePrefix}}languages ADD mem_overhead INTEGER NOT NULL DEFAULT 0;
+-- JVM startup is slow, give Java more room by default
+-- {{if eq .Driver "sqlserver"}}
+EXEC('UPDATE {{.TablePrefix}}languages SET time_multiplier = 2, time_offset = 1000 WHERE ext_name = ''java''');
+-- {{else}}
+UPDATE {{.TablePrefix}}languages SET time_multiplier = 2, time_offset = 1000 WHERE ext_name = 'java';
+-- {{end}}
@@ -0,0 +1,14 @@
+-- Problem time limit are stored in milliseconds instead of seconds
+-- Only rows still in seconds, so running again after interrupted never multiplies twice
+UPDATE {{.TablePrefix}}problems SET time_limit = time_limit * 1000 WHERE time_limit < 100;
+-- {{if eq .Driver "mysql"}}
+ALTER TABLE {{.TablePrefix}}problems ALTER COLUMN time_limit SET DEFAULT 1000;
+-- {{else if eq .Driver "sqlserver"}}
+-- Default constraint has generated name, find it before dropping
+DECLARE @timeLimitDefault NVARCHAR(128);
+SELECT @timeLimitDefault = dc.name FROM sys.default_constraints dc
+    JOIN sys.columns c ON (c.object_id = dc.parent_object_id) AND (c.column_id = dc.parent_column_id)
+    WHERE (dc.parent_object_id = OBJECT_ID('{{.TablePrefix}}problems')) AND (c.name = 'time_limit');
+IF @timeLimitDefault IS NOT NULL EXEC('ALTER TABLE {{.TablePrefix}}problems DROP CONSTRAINT ' + @timeLimitDefault);
+ALTER TABLE {{.TablePrefix}}problems ADD DEFAULT 1000 FOR time_limit;
+-- {{end}}
@@ -0,0 +1,2 @@
+-- All measured run times of a test case, for borderline time re-run review
+ALTER TABLE {{.TablePrefix}}testresults ADD time_samples VARCHAR(200) NOT NULL DEFAULT '';
@@ -0,0 +1,9 @@
+-- Persistent judging queue state and lease expiration time
+ALTER TABLE {{.TablePrefix}}submissions ADD judge_state VARCHAR(10) NOT NULL DEFAULT 'queued';
+ALTER TABLE {{.TablePrefix}}submissions ADD lease_time INTEGER NOT NULL DEFAULT 0;
+-- Only submission still on queue need to be judged
+-- {{if eq .Driver "sqlserver"}}
+EXEC('UPDATE {{.TablePrefix}}submissions SET judge_state = ''done'' WHERE verdict <> ''QU''');
+-- {{else}}
+UPDATE {{.TablePrefix}}submissions SET judge_state = 'done' WHERE verdict <> 'QU';
+-- {{end}}
@@ -0,0 +1,3 @@
+-- Slave scheduling weight and maximum concurrent judging (0 means unlimited)
+ALTER TABLE {{.TablePrefix}}slaves ADD weight INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE {{.TablePrefix}}slaves ADD capacity INTEGER NOT NULL DEFAULT 2;
@@ -0,0 +1,2 @@
+-- Address of slave which judged the submission
+ALTER TABLE {{.TablePrefix}}submissions ADD judged_by VARCHAR(200) NOT NULL DEFAULT '';
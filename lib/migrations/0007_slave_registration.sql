@@ -0,0 +1,3 @@
+-- Self-registered slave need approval, and its last heartbeat time
+ALTER TABLE {{.TablePrefix}}slaves ADD approved INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE {{.TablePrefix}}slaves ADD heartbeat_time INTEGER NOT NULL DEFAULT 0;
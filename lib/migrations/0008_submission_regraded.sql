@@ -0,0 +1,2 @@
+-- Mark submission which verdict changed by rejudge
+ALTER TABLE {{.TablePrefix}}submissions ADD regraded INTEGER NOT NULL DEFAULT 0;
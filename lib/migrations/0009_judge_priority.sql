@@ -0,0 +1,2 @@
+-- Judging priority class, higher one dispatched first
+ALTER TABLE {{.TablePrefix}}submissions ADD judge_priority INTEGER NOT NULL DEFAULT 1;
@@ -0,0 +1,17 @@
+-- Verdict mismatches found by double judging, waiting for jury review
+CREATE TABLE {{.TablePrefix}}judge_mismatches (
+    id INTEGER PRIMARY KEY {{.AutoIncrement}},
+    id_submission INTEGER NOT NULL,
+    slave_a VARCHAR(200) NOT NULL,
+    verdict_a VARCHAR(4) NOT NULL,
+    score_a INTEGER NOT NULL DEFAULT 0,
+    result_a TEXT NOT NULL,
+    slave_b VARCHAR(200) NOT NULL,
+    verdict_b VARCHAR(4) NOT NULL,
+    score_b INTEGER NOT NULL DEFAULT 0,
+    result_b TEXT NOT NULL,
+    create_time INTEGER NOT NULL DEFAULT 0,
+    resolution VARCHAR(10) NOT NULL DEFAULT '',
+    resolved_by INTEGER NOT NULL DEFAULT 0,
+    resolve_time INTEGER NOT NULL DEFAULT 0
+);
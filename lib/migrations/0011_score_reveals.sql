@@ -0,0 +1,9 @@
+-- Frozen scores revealed on public scoreboard by resolver
+CREATE TABLE {{.TablePrefix}}score_reveals (
+    id_contest INTEGER NOT NULL,
+    id_problem INTEGER NOT NULL,
+    id_user INTEGER NOT NULL,
+    reveal_time INTEGER NOT NULL DEFAULT 0,
+    revealed_by INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY (id_contest, id_problem, id_user)
+);
@@ -0,0 +1,11 @@
+-- Public scoreboard synced with private one after unfreeze
+ALTER TABLE {{.TablePrefix}}contests ADD unfrozen INTEGER NOT NULL DEFAULT 0;
+
+-- Actions done by jury, admin or system (id_user 0)
+CREATE TABLE {{.TablePrefix}}audit_logs (
+    id INTEGER PRIMARY KEY {{.AutoIncrement}},
+    id_user INTEGER NOT NULL DEFAULT 0,
+    action VARCHAR(50) NOT NULL,
+    details VARCHAR(200) NOT NULL DEFAULT '',
+    create_time INTEGER NOT NULL DEFAULT 0
+);
@@ -0,0 +1,4 @@
+-- Time when judging of submission last finished
+ALTER TABLE {{.TablePrefix}}submissions ADD judged_time INTEGER NOT NULL DEFAULT 0;
+-- Finish time never recorded before, submit time is closest known
+UPDATE {{.TablePrefix}}submissions SET judged_time = submit_time WHERE (judged_time = 0) AND (judged_by <> '');